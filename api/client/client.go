@@ -13,6 +13,7 @@ import (
         "crypto/sha256"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -46,7 +47,13 @@ func (c *Client) get(apiClientContext *contexter.APIClient, reqInfo *reqInfo) ([
 	if err != nil {
 		return  nil, errors.Errorf("can not parse url (%v)", reqInfo.url)
 	}
-	httpClient := helper.NewHTTPClient(u.Scheme, u.Host, apiClientContext.TLSSkipVerify, apiClientContext.Timeout)
+	httpClient, err := helper.NewHTTPClient(u.Scheme, u.Host, &helper.HTTPClientOption{
+		TLSSkipVerify: apiClientContext.TLSSkipVerify,
+		Timeout:       apiClientContext.Timeout,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("can not create http client (%v)", reqInfo.url))
+	}
 	request, err := http.NewRequest("GET", reqInfo.url, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("can not create request (%v)", reqInfo.url))
@@ -123,6 +130,45 @@ func (c *Client) GetWatchResult() (watchResultResponse *structure.WatchResultRes
 	return watchResultResponse, nil
 }
 
+// GetWatchResultMap is get watcher result from all api servers in parallel, map key is url base of api server
+func (c *Client) GetWatchResultMap() (map[string]*structure.WatchResultResponse, error) {
+	apiClientContext := c.context.GetAPIClient()
+	resource := "/v1/watch/result"
+	mutex := new(sync.Mutex)
+	waitGroup := new(sync.WaitGroup)
+	watchResultResponseMap := make(map[string]*structure.WatchResultResponse)
+	for _, apiServerURL := range apiClientContext.APIServerURLList {
+		urlBase := apiServerURL.String()
+		info := &reqInfo {
+			urlBase:  urlBase,
+			url:      urlBase + resource,
+			resource: resource,
+		}
+		waitGroup.Add(1)
+		go func(reqInfo *reqInfo) {
+			defer waitGroup.Done()
+			response, err := c.retryRequest(apiClientContext, c.get, reqInfo)
+			if err != nil {
+				belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not get watcher result (%v)", reqInfo.url)))
+				return
+			}
+			watchResultResponse := new(structure.WatchResultResponse)
+			if err := json.Unmarshal(response, watchResultResponse); err != nil {
+				belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not unmarshal response (%v)", reqInfo.url)))
+				return
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			watchResultResponseMap[reqInfo.urlBase] = watchResultResponse
+		}(info)
+	}
+	waitGroup.Wait()
+	if len(watchResultResponseMap) == 0 {
+		return nil, errors.Errorf("can not get watcher result from any api server (%v)", resource)
+	}
+	return watchResultResponseMap, nil
+}
+
 // New is create client
 func New(context *contexter.Context) (*Client) {
         return &Client {
@@ -66,6 +66,44 @@ func (s *Server) jsonResponse(context *gin.Context, object interface{}) {
 func (s *Server) contextToWatchResultResponse() (*structure.WatchResultResponse) {
 	newWatchResultResponse := &structure.WatchResultResponse {
 		ZoneMap : make(map[string]*structure.ZoneWatchResultResponse),
+		TargetMap : make(map[string]*structure.TargetWatchResultResponse),
+		MaintenanceMap : make(map[string]*structure.MaintenanceWatchResultResponse),
+	}
+	targetNameList := s.contexter.Context.Watcher.GetTargetNameList()
+	for _, targetName := range targetNameList {
+		target, err := s.contexter.Context.Watcher.GetTarget(targetName)
+		if err != nil {
+			belog.Notice("%v", err)
+			continue
+		}
+		newTargetWatchResultResponse := &structure.TargetWatchResultResponse {
+			Alive:       target.GetAlive(),
+			Unreachable: target.GetUnreachable(),
+			Detail:      target.GetDetail(),
+		}
+		if metric := target.GetMetric(); metric != nil {
+			newTargetWatchResultResponse.Metric = &structure.TargetMetricResponse {
+				LossPercent: metric.LossPercent,
+				MinRTT:      metric.MinRTT,
+				AvgRTT:      metric.AvgRTT,
+				MaxRTT:      metric.MaxRTT,
+				Latency:     metric.Latency,
+			}
+		}
+		newWatchResultResponse.TargetMap[targetName] = newTargetWatchResultResponse
+	}
+	maintenanceNameList := s.contexter.Context.Watcher.GetMaintenanceNameList()
+	for _, maintenanceName := range maintenanceNameList {
+		maintenance, err := s.contexter.Context.Watcher.GetMaintenance(maintenanceName)
+		if err != nil {
+			belog.Notice("%v", err)
+			continue
+		}
+		newWatchResultResponse.MaintenanceMap[maintenanceName] = &structure.MaintenanceWatchResultResponse {
+			Active:      maintenance.GetActive(),
+			Description: maintenance.String(),
+			Comment:     maintenance.Comment,
+		}
 	}
 	domainList := s.contexter.Context.Watcher.GetDomainList()
 	for _, domain := range domainList {
@@ -116,8 +154,12 @@ func (s *Server) contextToWatchResultResponse() (*structure.WatchResultResponse)
 					TTL:     record.TTL,
 					Content: record.Content,
 					Alive:   record.GetAlive(),
+					Flapping:            record.GetFlapping(),
+					MaintenanceNameList: record.GetMaintenanceNameList(),
+					GroupName:           dynamicGroupName,
 				}
-				if record.GetForceDown() {
+				if record.GetForceDown() || record.InMaintenance() {
+					// records in maintenance are treated as force down
 					newRecordWatchResultResponse.Alive = false
 				}
 				if newRecordWatchResultResponse.Alive {
@@ -136,6 +178,7 @@ func (s *Server) contextToWatchResultResponse() (*structure.WatchResultResponse)
 					TTL:     record.TTL,
 					Content: record.Content,
 					Alive:   negativeRecordAlive,
+					GroupName:           dynamicGroupName,
 				}
 				newZoneWatchResultResponse.DynamicRecordList = append(newZoneWatchResultResponse.DynamicRecordList, newRecordWatchResultResponse)
 			}
@@ -252,19 +295,82 @@ func (s *Server) target(context *gin.Context) {
 			context.String(http.StatusBadRequest, "{\"reason\":\"no domain\"}")
 			return
 		}
+		var tcpStepList []*contexter.TCPStep
+		if targetRequest.TCPStepList != nil {
+			tcpStepList = make([]*contexter.TCPStep, 0, len(targetRequest.TCPStepList))
+			for _, tcpStepRequest := range targetRequest.TCPStepList {
+				tcpStepList = append(tcpStepList, &contexter.TCPStep {
+					Send:    tcpStepRequest.Send,
+					SendHex: tcpStepRequest.SendHex,
+					Expect:  tcpStepRequest.Expect,
+					Timeout: tcpStepRequest.Timeout,
+				})
+			}
+		}
+		var httpJSONAssertionList []*contexter.JSONAssertion
+		if targetRequest.HTTPJSONAssertionList != nil {
+			httpJSONAssertionList = make([]*contexter.JSONAssertion, 0, len(targetRequest.HTTPJSONAssertionList))
+			for _, jsonAssertionRequest := range targetRequest.HTTPJSONAssertionList {
+				httpJSONAssertionList = append(httpJSONAssertionList, &contexter.JSONAssertion {
+					Path:     jsonAssertionRequest.Path,
+					Operator: jsonAssertionRequest.Operator,
+					Value:    jsonAssertionRequest.Value,
+				})
+			}
+		}
 		newTarget := &contexter.Target {
+			Template:              targetRequest.Template,
 			Protocol:           targetRequest.Protocol,
 			Dest:               targetRequest.Dest,
 			TCPTLS:             targetRequest.TCPTLS,
+			TCPStepList:           tcpStepList,
+			Payload:               targetRequest.Payload,
+			PayloadHex:            targetRequest.PayloadHex,
 			HTTPMethod:         targetRequest.HTTPMethod,
 			HTTPStatusList:     targetRequest.HTTPStatusList,
+			HTTPHeaderMap:         targetRequest.HTTPHeaderMap,
+			HTTPBody:              targetRequest.HTTPBody,
+			HTTPRedirectPolicy:    targetRequest.HTTPRedirectPolicy,
+			HTTPJSONAssertionList: httpJSONAssertionList,
+			DNSName:               targetRequest.DNSName,
+			DNSQtype:              targetRequest.DNSQtype,
+			DNSTransport:          targetRequest.DNSTransport,
+			DNSRecursionDesired:   targetRequest.DNSRecursionDesired,
+			DNSRcodeList:          targetRequest.DNSRcodeList,
+			ExecArgList:           targetRequest.ExecArgList,
+			ExecEnvMap:            targetRequest.ExecEnvMap,
+			GRPCServiceName:       targetRequest.GRPCServiceName,
+			GRPCTLS:               targetRequest.GRPCTLS,
+			DBUser:                targetRequest.DBUser,
+			DBPassword:            targetRequest.DBPassword,
+			DBName:                targetRequest.DBName,
+			DBQuery:               targetRequest.DBQuery,
+			DBExpectedValue:       targetRequest.DBExpectedValue,
+			DBTLS:                 targetRequest.DBTLS,
 			Regexp:             targetRequest.Regexp,
 			ResSize:            targetRequest.ResSize,
+			ICMPCount:             targetRequest.ICMPCount,
+			ICMPInterval:          targetRequest.ICMPInterval,
+			ICMPMaxLoss:           targetRequest.ICMPMaxLoss,
+			ICMPMaxAvgRTT:         targetRequest.ICMPMaxAvgRTT,
 			Retry:              targetRequest.Retry,
 			RetryWait:          targetRequest.RetryWait,
 			Timeout:            targetRequest.Timeout,
 			TLSSkipVerify:      targetRequest.TLSSkipVerify,
-		}
+			TLSServerName:         targetRequest.TLSServerName,
+			TLSCAFile:             targetRequest.TLSCAFile,
+			TLSClientCertFile:     targetRequest.TLSClientCertFile,
+			TLSClientKeyFile:      targetRequest.TLSClientKeyFile,
+			TLSCertExpireDays:     targetRequest.TLSCertExpireDays,
+			RiseCount:             targetRequest.RiseCount,
+			FallCount:             targetRequest.FallCount,
+			ParentTargetNameList:  targetRequest.ParentTargetNameList,
+			WatchInterval:         targetRequest.WatchInterval,
+			WatchIntervalMsec:     targetRequest.WatchIntervalMsec,
+			WatchJitter:           targetRequest.WatchJitter,
+		}
+		// fields not specified in request are inherited from template
+		newTarget.SetSpecifiedFieldNameList(targetRequest.GetFieldNameList())
 		if err := s.contexter.Context.Watcher.AddTarget(targetRequest.TargetName, newTarget); err != nil {
 			context.String(http.StatusBadRequest, "{\"reason\":\"%v\"}", err)
 			return
@@ -287,7 +393,25 @@ func (s *Server) targetTargetName(context *gin.Context) {
 		if context.Request.Method == http.MethodHead {
 			context.Status(http.StatusOK)
 		} else {
-			s.jsonResponse(context, target)
+			// embedded target is effective target that template is resolved
+			targetResponse := &struct {
+				*contexter.Target
+				Raw    *contexter.Target               `json:"raw"`
+				Status *structure.TargetStatusResponse `json:"status"`
+			}{
+				Target: target,
+				Raw:    target.GetRaw(),
+				Status: &structure.TargetStatusResponse {
+					Alive:               target.GetAlive(),
+					Unreachable:         target.GetUnreachable(),
+					Progress:            target.GetProgress(),
+					LastProbeTime:       target.GetLastProbeTime(),
+					MissedDeadlineCount: target.GetMissedDeadlineCount(),
+					RiseCounter:         target.GetRiseCounter(),
+					FallCounter:         target.GetFallCounter(),
+				},
+			}
+			s.jsonResponse(context, targetResponse)
 		}
 		return
         case http.MethodPut:
@@ -297,10 +421,18 @@ func (s *Server) targetTargetName(context *gin.Context) {
 			return
 		}
 		newTarget :=  new(contexter.Target)
-		if err := context.BindJSON(target); err != nil {
+		if err := context.BindJSON(newTarget); err != nil {
 			context.String(http.StatusBadRequest, "{\"reason\":\"can not unmarshal\"}")
 			return
 		}
+		if err := s.contexter.Context.Watcher.ResolveTargetTemplate(newTarget); err != nil {
+			context.String(http.StatusBadRequest, "{\"reason\":\"%v\"}", err)
+			return
+		}
+		if err := s.contexter.Context.Watcher.CheckTargetDependency(context.Param("tgname"), newTarget); err != nil {
+			context.String(http.StatusBadRequest, "{\"reason\":\"%v\"}", err)
+			return
+		}
 		target.Update(newTarget)
 		context.Status(http.StatusOK)
 		return
@@ -333,6 +465,134 @@ func (s *Server) targetTargetName(context *gin.Context) {
 
 
 
+func (s *Server) targetTargetNameHistory(context *gin.Context) {
+        switch context.Request.Method {
+	case http.MethodHead:
+		fallthrough
+	case http.MethodGet:
+		target, err := s.getTarget(context)
+		if err != nil {
+			context.String(http.StatusBadRequest, "{\"reason\":\"%v\"}", err)
+			return
+		}
+		if context.Request.Method == http.MethodHead {
+			context.Status(http.StatusOK)
+		} else {
+			probeHistory := target.GetProbeHistory()
+			probeResultResponseList := make([]*structure.ProbeResultResponse, 0, len(probeHistory))
+			for _, probeResult := range probeHistory {
+				probeResultResponseList = append(probeResultResponseList, &structure.ProbeResultResponse {
+					Time:     probeResult.Time,
+					Duration: probeResult.Duration,
+					Success:  probeResult.Success,
+					Error:    probeResult.Error,
+				})
+			}
+			s.jsonResponse(context, probeResultResponseList)
+		}
+		return
+	}
+}
+
+func (s Server) getMaintenance(context *gin.Context) (*contexter.Maintenance, error) {
+	maintenanceName := context.Param("mtname")
+	if maintenanceName == "" {
+		return nil, errors.Errorf("lack of maintenance name")
+	}
+	maintenance, err := s.contexter.Context.Watcher.GetMaintenance(maintenanceName)
+	if err != nil {
+		return nil, err
+	}
+	return maintenance, nil
+}
+
+func (s *Server) maintenance(context *gin.Context) {
+        switch context.Request.Method {
+        case http.MethodHead:
+		context.Status(http.StatusOK)
+		return
+        case http.MethodGet:
+		maintenanceNameList := s.contexter.Context.Watcher.GetMaintenanceNameList()
+		s.jsonResponse(context, maintenanceNameList)
+		return
+        case http.MethodPost:
+		var maintenanceRequest structure.MaintenanceRequest
+		if err := context.BindJSON(&maintenanceRequest); err != nil {
+			context.String(http.StatusBadRequest, "{\"reason\":\"can not unmarshal\"}")
+			return
+		}
+		if !maintenanceRequest.Validate() {
+			context.String(http.StatusBadRequest, "{\"reason\":\"lack of parameter\"}")
+			return
+		}
+		if maintenanceRequest.TargetName != "" {
+			if _, err := s.contexter.Context.Watcher.GetTarget(maintenanceRequest.TargetName); err != nil {
+				context.String(http.StatusBadRequest, "{\"reason\":\"%v\"}", err)
+				return
+			}
+		}
+		newMaintenance := &contexter.Maintenance {
+			Start:         maintenanceRequest.Start,
+			End:           maintenanceRequest.End,
+			Cron:          maintenanceRequest.Cron,
+			Duration:      maintenanceRequest.Duration,
+			TargetName:    maintenanceRequest.TargetName,
+			Domain:        maintenanceRequest.Domain,
+			GroupName:     maintenanceRequest.GroupName,
+			RecordName:    maintenanceRequest.RecordName,
+			RecordType:    maintenanceRequest.RecordType,
+			RecordContent: maintenanceRequest.RecordContent,
+			Comment:       maintenanceRequest.Comment,
+		}
+		if err := s.contexter.Context.Watcher.AddMaintenance(maintenanceRequest.MaintenanceName, newMaintenance); err != nil {
+			context.String(http.StatusBadRequest, "{\"reason\":\"%v\"}", err)
+			return
+		}
+		context.Status(http.StatusCreated)
+		return
+	}
+}
+
+func (s *Server) maintenanceMaintenanceName(context *gin.Context) {
+        switch context.Request.Method {
+	case http.MethodHead:
+		fallthrough
+	case http.MethodGet:
+		maintenance, err := s.getMaintenance(context)
+		if err != nil {
+			context.String(http.StatusBadRequest, "{\"reason\":\"%v\"}", err)
+			return
+		}
+		if context.Request.Method == http.MethodHead {
+			context.Status(http.StatusOK)
+		} else {
+			maintenanceResponse := &struct {
+				*contexter.Maintenance
+				Status *structure.MaintenanceStatusResponse `json:"status"`
+			}{
+				Maintenance: maintenance,
+				Status: &structure.MaintenanceStatusResponse {
+					Active: maintenance.GetActive(),
+				},
+			}
+			s.jsonResponse(context, maintenanceResponse)
+		}
+		return
+        case http.MethodDelete:
+		maintenanceName := context.Param("mtname")
+		if maintenanceName == "" {
+			context.String(http.StatusBadRequest, "{\"reason\":\"no maintenance name\"}")
+			return
+		}
+		if err := s.contexter.Context.Watcher.DeleteMaintenance(maintenanceName); err != nil {
+			context.String(http.StatusBadRequest, "{\"reason\":\"%v\"}", err)
+			return
+		}
+		context.Status(http.StatusOK)
+		return
+	}
+}
+
 func (s Server) getZone(context *gin.Context) (*contexter.Zone, error) {
 	domain := context.Param("domain")
 	if domain == "" {
@@ -886,6 +1146,56 @@ func (s *Server) zoneDynamicGroupDynamicRecordNTCForceDown(context *gin.Context)
 	}
 }
 
+func (s *Server) zoneDynamicGroupDynamicRecordNTCHistory(context *gin.Context) {
+        switch context.Request.Method {
+        case http.MethodHead:
+		fallthrough
+        case http.MethodGet:
+		dynamicGroup, err := s.getDynamicGroup(context)
+		if err != nil {
+			context.String(http.StatusBadRequest, "{\"reason\":\"%v\"}", err)
+			return
+		}
+		n := context.Param("name")
+		t := context.Param("type")
+		c := context.Param("Content")
+		if n == "" || t == "" || c == "" {
+			context.String(http.StatusBadRequest, "{\"reason\":\"lack of parameter\"}")
+			return
+		}
+		dynamicRecord := dynamicGroup.FindDynamicRecord(n, t, c)
+		if len(dynamicRecord) == 0 {
+			context.String(http.StatusNotFound, "{\"reason\":\"not found\"}")
+			return
+		}
+		if context.Request.Method == http.MethodHead {
+			context.Status(http.StatusOK)
+			return
+		}
+		dynamicRecordHistoryResponseList := make([]*structure.DynamicRecordHistoryResponse, 0, len(dynamicRecord))
+		for _, record := range dynamicRecord {
+			transitionHistory := record.GetAliveTransitionHistory()
+			aliveTransitionResponseList := make([]*structure.AliveTransitionResponse, 0, len(transitionHistory))
+			for _, aliveTransition := range transitionHistory {
+				aliveTransitionResponseList = append(aliveTransitionResponseList, &structure.AliveTransitionResponse {
+					Time:     aliveTransition.Time,
+					OldAlive: aliveTransition.OldAlive,
+					NewAlive: aliveTransition.NewAlive,
+					Flapping: aliveTransition.Flapping,
+				})
+			}
+			dynamicRecordHistoryResponseList = append(dynamicRecordHistoryResponseList, &structure.DynamicRecordHistoryResponse {
+				Name:                record.Name,
+				Type:                record.Type,
+				Content:             record.Content,
+				AliveTransitionList: aliveTransitionResponseList,
+			})
+		}
+		s.jsonResponse(context, dynamicRecordHistoryResponseList)
+		return
+	}
+}
+
 func (s *Server) zoneDynamicGroupNegativeRecord(context *gin.Context) {
         switch context.Request.Method {
         case http.MethodHead:
@@ -5,6 +5,7 @@ import (
         "github.com/braintree/manners"
         "github.com/gin-gonic/gin"
 	"github.com/potix/pdns-record-updater/contexter"
+	"github.com/potix/pdns-record-updater/metrics"
         "net/http"
         "path/filepath"
         "time"
@@ -65,9 +66,15 @@ func (s *Server) Start() (err error) {
                 errors.Errorf("not found linten port")
         }
 	engine := gin.Default()
+	engine.Use(metrics.CountAPIRequest)
 	var newGroup *gin.RouterGroup
 
 	// set up resource
+	if apiServerContext.MetricsNoAuth {
+		engine.GET("/metrics", metrics.GinHandler()) // メトリクス取得 (prometheusからscrapeするため認証しない)
+	} else {
+		engine.GET("/metrics", s.authHandler, metrics.GinHandler()) // メトリクス取得
+	}
 	newGroup = engine.Group("/v1", s.authHandler, s.commonHandler)
 	s.addGetHandler(newGroup, "/watch/result", s.watchResult) // 監視結果取得
 	s.addGetHandler(newGroup, "/config", s.config) // 設定取得
@@ -78,9 +85,15 @@ func (s *Server) Start() (err error) {
 	s.addGetHandler(newGroup, "/target", s.target)  // ターゲット一覧取得
 	s.addPostHandler(newGroup, "/target", s.target)  // ターゲット作成
 	s.addGetHandler(newGroup, "/target/:tgname", s.targetTargetName)  // ターゲット情報取得
+	s.addGetHandler(newGroup, "/target/:tgname/history", s.targetTargetNameHistory)  // ターゲットの監視履歴取得
 	s.addPutHandler(newGroup, "/target/:tgname", s.targetTargetName)  // ターゲット情報変更
 	s.addDeleteHandler(newGroup, "/target/:tgname", s.targetTargetName)  // ターゲット削除
 
+	s.addGetHandler(newGroup, "/maintenance", s.maintenance)  // メンテナンス一覧取得
+	s.addPostHandler(newGroup, "/maintenance", s.maintenance)  // メンテナンス作成
+	s.addGetHandler(newGroup, "/maintenance/:mtname", s.maintenanceMaintenanceName)  // メンテナンス情報取得
+	s.addDeleteHandler(newGroup, "/maintenance/:mtname", s.maintenanceMaintenanceName)  // メンテナンス削除
+
 
 	s.addGetHandler(newGroup, "/zone", s.zone)  // ゾーン一覧取得
 	s.addPostHandler(newGroup, "/zone", s.zone)  // ゾーン作成
@@ -105,6 +118,7 @@ func (s *Server) Start() (err error) {
 	s.addGetHandler(newGroup, "/zone/:domain/dynamicgroup/:dgname/dynamicrecord/:name/:type/:Content", s.zoneDynamicGroupDynamicRecordNTC)                    // 動的レコードの取得
 	s.addPostHandler(newGroup, "/zone/:domain/dynamicgroup/:dgname/dynamicrecord/:name/:type/:Content", s.zoneDynamicGroupDynamicRecordNTC)                   // 動的レコードの変更
 	s.addPutHandler(newGroup, "/zone/:domain/dynamicgroup/:dgname/dynamicrecord/:name/:type/:Content/forcedown", s.zoneDynamicGroupDynamicRecordNTCForceDown) // 動的レコードの変更
+	s.addGetHandler(newGroup, "/zone/:domain/dynamicgroup/:dgname/dynamicrecord/:name/:type/:Content/history", s.zoneDynamicGroupDynamicRecordNTCHistory)    // 動的レコードの生存フラグの遷移履歴取得
 	s.addDeleteHandler(newGroup, "/zone/:domain/dynamicgroup/:dgname/dynamicrecord/:name/:type/:Content", s.zoneDynamicGroupDynamicRecordNTC)                 // 動的レコードの削除
 	s.addGetHandler(newGroup, "/zone/:domain/dynamicgroup/:dgname/negativerecord", s.zoneDynamicGroupNegativeRecord)  // ネガティブレコードの一覧取得
 	s.addPostHandler(newGroup, "/zone/:domain/dynamicgroup/:dgname/negativerecord", s.zoneDynamicGroupNegativeRecord) // ネガティブレコードの作成
@@ -2,7 +2,9 @@ package structure
 
 import (
 	"github.com/potix/belog"
+	"encoding/json"
 	"strings"
+	"time"
 )
 
 // ConfigRequest is config
@@ -19,34 +21,124 @@ func (c ConfigRequest) Validate() (bool) {
 	return true
 }
 
+// TCPStepRequest is send/expect step of tcp
+type TCPStepRequest struct {
+        Send    string `json:"send"    yaml:"send"    toml:"send"`    // 送信するデータ (エスケープ文字列)
+        SendHex bool   `json:"sendHex" yaml:"sendHex" toml:"sendHex"` // 送信するデータを16進数文字列として扱う
+        Expect  string `json:"expect"  yaml:"expect"  toml:"expect"`  // OKとみなす正規表現
+        Timeout uint32 `json:"timeout" yaml:"timeout" toml:"timeout"` // ステップのタイムアウト
+}
+
+// JSONAssertionRequest is assertion of json response
+type JSONAssertionRequest struct {
+        Path     string `json:"path"     yaml:"path"     toml:"path"`     // gjson形式のパス
+        Operator string `json:"operator" yaml:"operator" toml:"operator"` // 比較演算子 eq, ne, gt, ge, lt, le, exists, regexp
+        Value    string `json:"value"    yaml:"value"    toml:"value"`    // 期待する値
+}
+
 // TargetRequest is config of target
 type TargetRequest struct {
 	TargetName     string   `json:"targetName"     yaml:"targetName"     toml:"targetName"`
-        Protocol       string   `json:"protocol"       yaml:"protocol"       toml:"protocol"`       // プロトコル icmp, udp, udpRegexp, tcp, tcpRegexp, http, httpRegexp
+        Template              string                  `json:"template"              yaml:"template"              toml:"template"`              // テンプレート名 テンプレートの値を引き継ぎ、指定したフィールドだけ上書きする
+        Protocol       string   `json:"protocol"       yaml:"protocol"       toml:"protocol"`       // プロトコル icmp, udp, udpRegexp, tcp, tcpRegexp, http, httpRegexp, httpJson, dns, tlsCert, exec, grpc, mysql, postgres
         Dest           string   `json:"dest"           yaml:"dest"           toml:"dest"`           // 宛先
         TCPTLS         bool     `json:"tcpTls"         yaml:"tcpTls"         toml:"tcpTls"`         // TCPにTLSを使う
+        TCPStepList           []*TCPStepRequest       `json:"tcpStepList"           yaml:"tcpStepList"           toml:"tcpStepList"`           // TCPで順に実行する送信と期待する応答のリスト
+        Payload               string                  `json:"payload"               yaml:"payload"               toml:"payload"`               // UDPで送信するペイロード (エスケープ文字列)
+        PayloadHex            bool                    `json:"payloadHex"            yaml:"payloadHex"            toml:"payloadHex"`            // ペイロードを16進数文字列として扱う
         HTTPMethod     string   `json:"httpMethod"     yaml:"httpMethod"     toml:"httpMethod"`     // HTTPメソッド
         HTTPStatusList []string `json:"httpStatusList" yaml:"httpStatusList" toml:"httpStatusList"` // OKとみなすHTTPステータスコード
+        HTTPHeaderMap         map[string]string       `json:"httpHeaderMap"         yaml:"httpHeaderMap"         toml:"httpHeaderMap"`         // HTTPで送信するヘッダー (Hostを含む)
+        HTTPBody              string                  `json:"httpBody"              yaml:"httpBody"              toml:"httpBody"`              // HTTPで送信するボディ
+        HTTPRedirectPolicy    string                  `json:"httpRedirectPolicy"    yaml:"httpRedirectPolicy"    toml:"httpRedirectPolicy"`    // HTTPのリダイレクトポリシー follow, none, sameHost
+        HTTPJSONAssertionList []*JSONAssertionRequest `json:"httpJsonAssertionList" yaml:"httpJsonAssertionList" toml:"httpJsonAssertionList"` // HTTPのJSONレスポンスに対するアサーションのリスト
+        DNSName               string                  `json:"dnsName"               yaml:"dnsName"               toml:"dnsName"`               // DNSで問い合わせる名前
+        DNSQtype              string                  `json:"dnsQtype"              yaml:"dnsQtype"              toml:"dnsQtype"`              // DNSで問い合わせるタイプ
+        DNSTransport          string                  `json:"dnsTransport"          yaml:"dnsTransport"          toml:"dnsTransport"`          // DNSのトランスポート udp, tcp, tls
+        DNSRecursionDesired   bool                    `json:"dnsRecursionDesired"   yaml:"dnsRecursionDesired"   toml:"dnsRecursionDesired"`   // DNSでRDビットを立てる
+        DNSRcodeList          []string                `json:"dnsRcodeList"          yaml:"dnsRcodeList"          toml:"dnsRcodeList"`          // OKとみなすDNSのRCODE
+        ExecArgList           []string                `json:"execArgList"           yaml:"execArgList"           toml:"execArgList"`           // execで実行するコマンドの引数 (コマンドはdestに指定)
+        ExecEnvMap            map[string]string       `json:"execEnvMap"            yaml:"execEnvMap"            toml:"execEnvMap"`            // execで実行するコマンドに追加する環境変数
+        GRPCServiceName       string                  `json:"grpcServiceName"       yaml:"grpcServiceName"       toml:"grpcServiceName"`       // gRPCのヘルスチェックで問い合わせるサービス名 (空の場合はサーバー全体)
+        GRPCTLS               bool                    `json:"grpcTls"               yaml:"grpcTls"               toml:"grpcTls"`               // gRPCにTLSを使う
+        DBUser                string                  `json:"dbUser"                yaml:"dbUser"                toml:"dbUser"`                // mysql, postgresで認証に使うユーザー名
+        DBPassword            string                  `json:"dbPassword"            yaml:"dbPassword"            toml:"dbPassword"`            // mysql, postgresで認証に使うパスワード
+        DBName                string                  `json:"dbName"                yaml:"dbName"                toml:"dbName"`                // mysql, postgresで接続するデータベース名
+        DBQuery               string                  `json:"dbQuery"               yaml:"dbQuery"               toml:"dbQuery"`               // mysql, postgresで実行するクエリ (空の場合は接続のみ)
+        DBExpectedValue       string                  `json:"dbExpectedValue"       yaml:"dbExpectedValue"       toml:"dbExpectedValue"`       // クエリが返す単一の値の期待値 (空の場合は比較しない)
+        DBTLS                 bool                    `json:"dbTls"                 yaml:"dbTls"                 toml:"dbTls"`                 // mysql, postgresにTLSを使う
         Regexp         string   `json:"regexp"         yaml:"regexp"         toml:"regexp"`         // OKとみなす正規表現
         ResSize        uint32   `json:"resSize"        yaml:"resSize"        toml:"resSize"`        // 受信する最大レスポンスサイズ
+        ICMPCount             uint32                  `json:"icmpCount"             yaml:"icmpCount"             toml:"icmpCount"`             // ICMPで一度に送信するエコーの数
+        ICMPInterval          uint32                  `json:"icmpInterval"          yaml:"icmpInterval"          toml:"icmpInterval"`          // ICMPエコーの送信間隔 (ミリ秒)
+        ICMPMaxLoss           uint32                  `json:"icmpMaxLoss"           yaml:"icmpMaxLoss"           toml:"icmpMaxLoss"`           // ダウンとみなすパケットロス率 (%) 0の場合は全てロスした場合のみ
+        ICMPMaxAvgRTT         uint32                  `json:"icmpMaxAvgRtt"         yaml:"icmpMaxAvgRtt"         toml:"icmpMaxAvgRtt"`         // ダウンとみなす平均RTT (ミリ秒) 0の場合は判定しない
         Retry          uint32   `json:"retry"          yaml:"retry"          toml:"retry"`          // リトライ回数
         RetryWait      uint32   `json:"retryWait"      yaml:"retryWait"      toml:"retryWait"`      // 次のリトライまでの待ち時間
         Timeout        uint32   `json:"timeout"        yaml:"timeout"        toml:"timeout"`        // タイムアウトしたとみなす時間
         TLSSkipVerify  bool     `json:"tlsSkipVerify"  yaml:"tlsSkipVerify"  toml:"tlsSkipVerify"`  // TLSの検証をスキップする
+        TLSServerName         string                  `json:"tlsServerName"         yaml:"tlsServerName"         toml:"tlsServerName"`         // TLSのサーバー名(SNI)
+        TLSCAFile             string                  `json:"tlsCaFile"             yaml:"tlsCaFile"             toml:"tlsCaFile"`             // TLSの検証に使うCAバンドルファイルパス
+        TLSClientCertFile     string                  `json:"tlsClientCertFile"     yaml:"tlsClientCertFile"     toml:"tlsClientCertFile"`     // TLSのクライアント証明書ファイルパス
+        TLSClientKeyFile      string                  `json:"tlsClientKeyFile"      yaml:"tlsClientKeyFile"      toml:"tlsClientKeyFile"`      // TLSのクライアントプライベートキーファイルパス
+        TLSCertExpireDays     uint32                  `json:"tlsCertExpireDays"     yaml:"tlsCertExpireDays"     toml:"tlsCertExpireDays"`     // 証明書の有効期限がこの日数以内ならダウンとみなす
+        RiseCount             uint32                  `json:"riseCount"             yaml:"riseCount"             toml:"riseCount"`             // 生存とみなすまでに連続して成功する回数
+        FallCount             uint32                  `json:"fallCount"             yaml:"fallCount"             toml:"fallCount"`             // ダウンとみなすまでに連続して失敗する回数
+        ParentTargetNameList  []string                `json:"parentTargetNameList"  yaml:"parentTargetNameList"  toml:"parentTargetNameList"`  // 親ターゲットのリスト 親がダウンしている間はダウンではなく到達不能とみなす
+        WatchInterval         uint32                  `json:"watchInterval"         yaml:"watchInterval"         toml:"watchInterval"`         // 監視する間隔
+        WatchIntervalMsec     uint32                  `json:"watchIntervalMsec"     yaml:"watchIntervalMsec"     toml:"watchIntervalMsec"`     // 監視する間隔 (ミリ秒) 指定した場合はwatchIntervalより優先
+        WatchJitter           uint32                  `json:"watchJitter"           yaml:"watchJitter"           toml:"watchJitter"`           // 監視する時刻に加えるランダムな遅延の最大値 (ミリ秒)
+        fieldNameList         []string                                                                                                                    // リクエストで指定されたフィールドのjson名
+}
+
+// targetRequest is same as TargetRequest without methods, in order to decode without recursion
+type targetRequest TargetRequest
+
+// UnmarshalJSON is decode target request and remember specified fields
+func (t *TargetRequest) UnmarshalJSON(data []byte) (error) {
+	var fieldMap map[string]interface{}
+	if err := json.Unmarshal(data, &fieldMap); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, (*targetRequest)(t)); err != nil {
+		return err
+	}
+	t.fieldNameList = make([]string, 0, len(fieldMap))
+	for fieldName := range fieldMap {
+		t.fieldNameList = append(t.fieldNameList, fieldName)
+	}
+	return nil
+}
+
+// GetFieldNameList is get json names of fields specified in request
+func (t *TargetRequest) GetFieldNameList() ([]string) {
+	return t.fieldNameList
 }
 
 // Validate is validate target request
 func (t *TargetRequest) Validate() (bool) {
-        if t.TargetName == "" ||  t.Protocol == "" || t.Dest == "" {
+        if t.TargetName == "" || (t.Protocol == "" && t.Template == "") || t.Dest == "" {
                 belog.Error("no name or no protocol or no dest")
                 return false
         }
-        if t.Protocol == "http" || t.Protocol == "httpRegexp" {
+        if t.Protocol == "http" || t.Protocol == "httpRegexp" || t.Protocol == "httpJson" {
                 if t.HTTPMethod == "" || t.HTTPStatusList == nil || len(t.HTTPStatusList) == 0 {
                         belog.Error("no httpMethod or no httpStatusList")
                         return false
                 }
         }
+        if t.Protocol == "dns" {
+                if t.DNSName == "" {
+                        belog.Error("no dnsName")
+                        return false
+                }
+        }
+        if t.Protocol == "mysql" || t.Protocol == "postgres" {
+                if t.DBUser == "" {
+                        belog.Error("no dbUser")
+                        return false
+                }
+        }
         return true
 }
 
@@ -101,3 +193,36 @@ type ZoneDynamicGroupDynamicRecordForceDownRequest struct {
         ForceDown bool `json:"forceDown"`
 }
 
+
+// MaintenanceRequest is maintenance
+type MaintenanceRequest struct {
+        MaintenanceName string    `json:"maintenanceName"`
+        Start           time.Time `json:"start"`
+        End             time.Time `json:"end"`
+        Cron            string    `json:"cron"`
+        Duration        uint32    `json:"duration"`
+        TargetName      string    `json:"targetName"`
+        Domain          string    `json:"domain"`
+        GroupName       string    `json:"groupName"`
+        RecordName      string    `json:"recordName"`
+        RecordType      string    `json:"recordType"`
+        RecordContent   string    `json:"recordContent"`
+        Comment         string    `json:"comment"`
+}
+
+// Validate is validate maintenance request
+func (m MaintenanceRequest) Validate() (bool) {
+	if m.MaintenanceName == "" {
+		belog.Warn("no maintenanceName")
+		return false
+	}
+	if m.Cron == "" && (m.Start.IsZero() || m.End.IsZero()) {
+		belog.Warn("no cron or no start/end")
+		return false
+	}
+	if m.TargetName == "" && m.Domain == "" {
+		belog.Warn("no targetName or no domain")
+		return false
+	}
+	return true
+}
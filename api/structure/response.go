@@ -1,6 +1,7 @@
 package structure
 
 import (
+	"time"
 	"fmt"
 )
 
@@ -27,6 +28,9 @@ type DynamicRecordWatchResultResponse struct {
         TTL     int32  `json:"ttl"`
         Content string `json:"content"`
         Alive   bool   `json:"alive"`
+        Flapping            bool     `json:"flapping"`
+        MaintenanceNameList []string `json:"maintenanceNameList"`
+        GroupName           string   `json:"groupName"`
 }
 
 // NameServerListWatchResultResponse is name server list
@@ -83,9 +87,75 @@ type ZoneWatchResultResponse struct {
 	DynamicRecordList DynamicRecordListWatchResultResponse `json:"dynamicRecordList"`
 }
 
+// TargetMetricResponse is target metric
+type TargetMetricResponse struct {
+        LossPercent float64 `json:"lossPercent"`
+        MinRTT      float64 `json:"minRtt"`
+        AvgRTT      float64 `json:"avgRtt"`
+        MaxRTT      float64 `json:"maxRtt"`
+        Latency     float64 `json:"latency"`
+}
+
+// TargetWatchResultResponse is target watch result
+type TargetWatchResultResponse struct {
+        Alive       bool                  `json:"alive"`
+        Unreachable bool                  `json:"unreachable"`
+        Detail      string                `json:"detail"`
+        Metric      *TargetMetricResponse `json:"metric"`
+}
+
+// TargetStatusResponse is target status
+type TargetStatusResponse struct {
+        Alive               bool      `json:"alive"`
+        Unreachable         bool      `json:"unreachable"`
+        Progress            bool      `json:"progress"`
+        LastProbeTime       time.Time `json:"lastProbeTime"`
+        MissedDeadlineCount uint32    `json:"missedDeadlineCount"`
+        RiseCounter         uint32    `json:"riseCounter"`
+        FallCounter         uint32    `json:"fallCounter"`
+}
+
+// ProbeResultResponse is probe result
+type ProbeResultResponse struct {
+        Time     time.Time `json:"time"`
+        Duration float64   `json:"duration"`
+        Success  bool      `json:"success"`
+        Error    string    `json:"error"`
+}
+
+// AliveTransitionResponse is alive transition
+type AliveTransitionResponse struct {
+        Time     time.Time `json:"time"`
+        OldAlive bool      `json:"oldAlive"`
+        NewAlive bool      `json:"newAlive"`
+        Flapping bool      `json:"flapping"`
+}
+
+// DynamicRecordHistoryResponse is alive transition history of dynamic record
+type DynamicRecordHistoryResponse struct {
+        Name                string                     `json:"name"`
+        Type                string                     `json:"type"`
+        Content             string                     `json:"content"`
+        AliveTransitionList []*AliveTransitionResponse `json:"aliveTransitionList"`
+}
+
+// MaintenanceWatchResultResponse is maintenance watch result
+type MaintenanceWatchResultResponse struct {
+        Active      bool   `json:"active"`
+        Description string `json:"description"`
+        Comment     string `json:"comment"`
+}
+
+// MaintenanceStatusResponse is maintenance status
+type MaintenanceStatusResponse struct {
+        Active bool `json:"active"`
+}
+
 // WatchResultResponse is watch result
 type WatchResultResponse struct {
 	ZoneMap map[string]*ZoneWatchResultResponse `json:"zoneMap"`
+	TargetMap      map[string]*TargetWatchResultResponse      `json:"targetMap"`
+	MaintenanceMap map[string]*MaintenanceWatchResultResponse `json:"maintenanceMap"`
 }
 
 // ZoneDomainResponse is zone domain
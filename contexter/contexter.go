@@ -7,13 +7,27 @@ import (
 	"encoding/json"
 	"gopkg.in/yaml.v2"
 	"github.com/potix/pdns-record-updater/configurator"
+	"github.com/potix/pdns-record-updater/evaluator"
+	"github.com/potix/pdns-record-updater/helper"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 	"bytes"
 	"strings"
+	"net/url"
+	"crypto/tls"
 )
 
 var mutableMutex *sync.Mutex
 
+const historySize = 100
+
+// maxMaintenanceDuration is max duration of recurring maintenance (7 days)
+const maxMaintenanceDuration = 7 * 24 * 60 * 60
+
 // NotifyTrigger is notify trigger
 type NotifyTrigger string
 
@@ -38,31 +52,51 @@ type DynamicRecord struct {
 	TTL                  int32           `json:"ttl"               yaml:"ttl"               toml:"ttl"`               // DNSレコードTTL 
 	Content              string          `json:"content"           yaml:"content"           toml:"content"`           // DNSレコード内容                  
 	TargetNameList       []string        `json:"targetNameList"    yaml:"targetNameList"    toml:"targetNameList"`    // ターゲットリスト
-	EvalRule             string          `json:"evalRule"          yaml:"evalRule"          toml:"evalRule"`          // 生存を判定する際のターゲットの評価ルール example: "(%(a) && (%(b) || !%(c))) || ((%(d) && %(e)) || !%(f))"  (a,b,c,d,e,f is target name)
+	EvalRule             string          `json:"evalRule"          yaml:"evalRule"          toml:"evalRule"`          // 生存を判定する際のターゲットの評価ルール example: "(%(a) && (%(b) || !%(c))) || ((%(d) && %(e)) || !%(f))"  (a,b,c,d,e,f is target name), "atLeast(2, a, b, c) && a.latency < 100", "atLeast(50%, a, b, c, d)"
 	Alive                bool            `json:"alive"             yaml:"alive"             toml:"alive"`             // 生存フラグ                       [mutable]
 	ForceDown            bool            `json:"forceDown"         yaml:"forceDown"         toml:"forceDown"`         // 強制的にダウンしたとみなすフラグ [mutable]
 	NotifyTriggerList    []NotifyTrigger `json:"notifyTriggerList" yaml:"notifyTriggerList" toml:"notifyTriggerList"` // notifierを送信するトリガー changed, latestDown, latestUp
-}
-
-func (d *DynamicRecord) validate() (bool) {
+	FlapWindow           uint32          `json:"flapWindow"        yaml:"flapWindow"        toml:"flapWindow"`        // フラップを検出する状態遷移の集計期間 (秒)
+	FlapThreshold        uint32          `json:"flapThreshold"     yaml:"flapThreshold"     toml:"flapThreshold"`     // 集計期間内の状態遷移の回数がこの値以上ならフラップとみなす 0の場合は検出しない
+	FlapDampingPeriod    uint32          `json:"flapDampingPeriod" yaml:"flapDampingPeriod" toml:"flapDampingPeriod"` // フラップ時にダウンした状態を保持する期間 (秒)
+	transitionTimeList   []time.Time                                                                                  // 評価結果が遷移した時刻のリスト [mutable]
+	lastEvalAlive        bool                                                                                         // 最後の評価結果 [mutable]
+	evaluated            bool                                                                                         // 評価済みフラグ [mutable]
+	flapping             bool                                                                                         // フラップ中フラグ [mutable]
+	dampedUntil          time.Time                                                                                    // ダウンした状態を保持する期限 [mutable]
+	evalRule             *evaluator.Rule                                                                              // コンパイルされた評価ルール [mutable]
+	transitionHistory    []*AliveTransition                                                                           // 生存フラグの遷移履歴 (リングバッファ) [mutable]
+	transitionHistoryPos int                                                                                          // 遷移履歴の次の書き込み位置 [mutable]
+	maintenanceNameList  []string                                                                                     // 適用中のメンテナンス名のリスト [mutable]
+	suppressedDown       bool                                                                                         // 到達不能のためダウンの通知を抑制したことを示すフラグ [mutable]
+}
+
+func (d *DynamicRecord) validate() (error) {
 	if d.Name == "" || d.Type == "" || d.TTL == 0 || d.Content == "" ||
            d.EvalRule == "" || d.TargetNameList == nil {
-		belog.Error("no name or no type or no ttl or no content or no watchInterval or no evalRule or no targetList")
-		return false
+		return errors.Errorf("no name or no type or no ttl or no content or no watchInterval or no evalRule or no targetList")
 	}
 	for _, targetName := range d.TargetNameList {
 		if targetName == "" {
-			return false
+			return errors.Errorf("empty target name in targetNameList")
 		}
 	}
+	evalRule, err := evaluator.Compile(d.EvalRule, d.TargetNameList)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("can not compile evalRule (%v)", d.EvalRule))
+	}
+	d.evalRule = evalRule
+	if d.FlapThreshold > 0 && d.FlapWindow == 0 {
+		return errors.Errorf("no flapWindow")
+	}
 	if d.NotifyTriggerList != nil {
 		for _, notifyTrigger := range d.NotifyTriggerList {
 			if !notifyTrigger.validate() {
-				return false
+				return errors.Errorf("invalid notifyTrigger (%v)", notifyTrigger)
 			}
 		}
 	}
-	return true
+	return nil
 }
 
 // SwapAlive is swap alive
@@ -81,6 +115,173 @@ func (d *DynamicRecord) GetAlive() (bool) {
 	return d.Alive
 }
 
+// GetEvalRule is get compiled eval rule
+func (d *DynamicRecord) GetEvalRule() (*evaluator.Rule, error) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	if d.evalRule == nil {
+		evalRule, err := evaluator.Compile(d.EvalRule, d.TargetNameList)
+		if err != nil {
+			return nil, err
+		}
+		d.evalRule = evalRule
+	}
+	return d.evalRule, nil
+}
+
+// UpdateFlapState is record transition of evaluated alive and update flapping state
+func (d *DynamicRecord) UpdateFlapState(evalAlive bool, now time.Time) (flapping bool, changed bool) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	if d.FlapThreshold == 0 {
+		changed = d.flapping
+		d.flapping = false
+		d.transitionTimeList = nil
+		return false, changed
+	}
+	if d.evaluated && d.lastEvalAlive != evalAlive {
+		d.transitionTimeList = append(d.transitionTimeList, now)
+	}
+	d.lastEvalAlive = evalAlive
+	d.evaluated = true
+	// drop transitions out of window
+	windowStart := now.Add(-time.Duration(d.FlapWindow) * time.Second)
+	i := 0
+	for i < len(d.transitionTimeList) && d.transitionTimeList[i].Before(windowStart) {
+		i++
+	}
+	d.transitionTimeList = d.transitionTimeList[i:]
+	score := uint32(len(d.transitionTimeList))
+	if score >= d.FlapThreshold {
+		// start or extend damping while transitions continue
+		d.dampedUntil = now.Add(time.Duration(d.FlapDampingPeriod) * time.Second)
+		if !d.flapping {
+			d.flapping = true
+			return true, true
+		}
+		return true, false
+	}
+	if d.flapping && now.After(d.dampedUntil) {
+		d.flapping = false
+		d.transitionTimeList = nil
+		return false, true
+	}
+	return d.flapping, false
+}
+
+// GetFlapping is get flapping
+func (d *DynamicRecord) GetFlapping() (bool) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	return d.flapping
+}
+
+// SetMaintenanceNameList is set name list of maintenance in effect
+func (d *DynamicRecord) SetMaintenanceNameList(maintenanceNameList []string) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	d.maintenanceNameList = maintenanceNameList
+}
+
+// GetMaintenanceNameList is get name list of maintenance in effect
+func (d *DynamicRecord) GetMaintenanceNameList() ([]string) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	return d.maintenanceNameList
+}
+
+// InMaintenance is whether dynamic record is in maintenance
+func (d *DynamicRecord) InMaintenance() (bool) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	return len(d.maintenanceNameList) > 0
+}
+
+// SwapSuppressedDown is swap suppressed down
+func (d *DynamicRecord) SwapSuppressedDown(suppressedDown bool) (bool) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	oldSuppressedDown := d.suppressedDown
+	d.suppressedDown = suppressedDown
+	return oldSuppressedDown
+}
+
+// AliveTransition is transition of alive
+type AliveTransition struct {
+	Time     time.Time `json:"time"`     // 遷移した時刻
+	OldAlive bool      `json:"oldAlive"` // 遷移前の生存フラグ
+	NewAlive bool      `json:"newAlive"` // 遷移後の生存フラグ
+	Flapping bool      `json:"flapping"` // フラップ中フラグ
+}
+
+// AddAliveTransition is add alive transition to history
+func (d *DynamicRecord) AddAliveTransition(aliveTransition *AliveTransition) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	if d.transitionHistory == nil {
+		d.transitionHistory = make([]*AliveTransition, 0, historySize)
+	}
+	if len(d.transitionHistory) < historySize {
+		d.transitionHistory = append(d.transitionHistory, aliveTransition)
+	} else {
+		d.transitionHistory[d.transitionHistoryPos] = aliveTransition
+	}
+	d.transitionHistoryPos = (d.transitionHistoryPos + 1) % historySize
+}
+
+// GetAliveTransitionHistory is get alive transition history in chronological order
+func (d *DynamicRecord) GetAliveTransitionHistory() ([]*AliveTransition) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	history := make([]*AliveTransition, 0, len(d.transitionHistory))
+	if len(d.transitionHistory) < historySize {
+		return append(history, d.transitionHistory...)
+	}
+	history = append(history, d.transitionHistory[d.transitionHistoryPos:]...)
+	return append(history, d.transitionHistory[:d.transitionHistoryPos]...)
+}
+
+// DynamicRecordState is runtime state of dynamic record
+type DynamicRecordState struct {
+	Alive              bool        `json:"alive"`              // 生存フラグ
+	ForceDown          bool        `json:"forceDown"`          // 強制的にダウンしたとみなすフラグ
+	TransitionTimeList []time.Time `json:"transitionTimeList"` // 評価結果が遷移した時刻のリスト
+	LastEvalAlive      bool        `json:"lastEvalAlive"`      // 最後の評価結果
+	Evaluated          bool        `json:"evaluated"`          // 評価済みフラグ
+	Flapping           bool        `json:"flapping"`           // フラップ中フラグ
+	DampedUntil        time.Time   `json:"dampedUntil"`        // ダウンした状態を保持する期限
+}
+
+// GetState is get runtime state
+func (d *DynamicRecord) GetState() (*DynamicRecordState) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	transitionTimeList := make([]time.Time, len(d.transitionTimeList))
+	copy(transitionTimeList, d.transitionTimeList)
+	return &DynamicRecordState{
+		Alive:              d.Alive,
+		ForceDown:          d.ForceDown,
+		TransitionTimeList: transitionTimeList,
+		LastEvalAlive:      d.lastEvalAlive,
+		Evaluated:          d.evaluated,
+		Flapping:           d.flapping,
+		DampedUntil:        d.dampedUntil,
+	}
+}
+
+// SetState is set runtime state
+func (d *DynamicRecord) SetState(state *DynamicRecordState) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	d.Alive = state.Alive
+	d.ForceDown = state.ForceDown
+	d.transitionTimeList = state.TransitionTimeList
+	d.lastEvalAlive = state.LastEvalAlive
+	d.evaluated = state.Evaluated
+	d.flapping = state.Flapping
+	d.dampedUntil = state.DampedUntil
+}
+
 // SetForceDown is set force down
 func (d *DynamicRecord) SetForceDown(forceDown bool) {
 	mutableMutex.Lock()
@@ -152,7 +353,8 @@ type DynamicGroup struct {
 func (d *DynamicGroup) validate() (bool) {
 	if d.DynamicRecordList != nil {
 		for _, dynamicRecord := range d.DynamicRecordList {
-			if !dynamicRecord.validate() {
+			if err := dynamicRecord.validate(); err != nil {
+				belog.Error("%v", err)
 				return false
 			}
 		}
@@ -207,8 +409,8 @@ func (d *DynamicGroup) FindDynamicRecord(n string, t string, c string) ([]*Dynam
 func (d *DynamicGroup) AddDynamicRecord(dynamicRecord *DynamicRecord) (error) {
 	mutableMutex.Lock()
 	defer mutableMutex.Unlock()
-	if !dynamicRecord.validate() {
-		return errors.Errorf("invalid dynamic record")
+	if err := dynamicRecord.validate(); err != nil {
+		return errors.Wrap(err, "invalid dynamic record")
 	}
 	if d.DynamicRecordList == nil {
 		d.DynamicRecordList = make([]*DynamicRecord, 0, 1)
@@ -250,8 +452,8 @@ func (d *DynamicGroup) ReplaceDynamicRecord(n string, t string, c string, dynami
 	replaced := false
 	mutableMutex.Lock()
 	defer mutableMutex.Unlock()
-	if !dynamicRecord.validate() {
-		return errors.Errorf("invalid dynamic record")
+	if err := dynamicRecord.validate(); err != nil {
+		return errors.Wrap(err, "invalid dynamic record")
 	}
 	if d.DynamicRecordList == nil {
 		d.DynamicRecordList = make([]*DynamicRecord, 0)
@@ -698,58 +900,477 @@ func (z *Zone) DeleteDynamicGroup(dynamicGroupName string) (error) {
 	return nil
 }
 
+// TCPStep is send/expect step of tcp
+type TCPStep struct {
+	Send    string `json:"send"    yaml:"send"    toml:"send"`    // 送信するデータ (エスケープ文字列)
+	SendHex bool   `json:"sendHex" yaml:"sendHex" toml:"sendHex"` // 送信するデータを16進数文字列として扱う
+	Expect  string `json:"expect"  yaml:"expect"  toml:"expect"`  // OKとみなす正規表現
+	Timeout uint32 `json:"timeout" yaml:"timeout" toml:"timeout"` // ステップのタイムアウト
+}
+
+func (t *TCPStep) validate() (bool) {
+	if t.Send == "" && t.Expect == "" {
+		belog.Error("no send and no expect")
+		return false
+	}
+	return true
+}
+
+// JSONAssertion is assertion of json response
+type JSONAssertion struct {
+	Path     string `json:"path"     yaml:"path"     toml:"path"`     // gjson形式のパス
+	Operator string `json:"operator" yaml:"operator" toml:"operator"` // 比較演算子 eq, ne, gt, ge, lt, le, exists, regexp
+	Value    string `json:"value"    yaml:"value"    toml:"value"`    // 期待する値
+}
+
+func (j *JSONAssertion) validate() (bool) {
+	if j.Path == "" {
+		belog.Error("no path")
+		return false
+	}
+	switch strings.ToUpper(j.Operator) {
+	case "", "EQ", "NE", "GT", "GE", "LT", "LE", "EXISTS", "REGEXP":
+	default:
+		belog.Error("unexpected operator")
+		return false
+	}
+	return true
+}
+
+// TargetMetric is measured value of target
+type TargetMetric struct {
+	LossPercent float64 `json:"lossPercent" yaml:"lossPercent" toml:"lossPercent"` // パケットロス率
+	MinRTT      float64 `json:"minRtt"      yaml:"minRtt"      toml:"minRtt"`      // 最小RTT (ミリ秒)
+	AvgRTT      float64 `json:"avgRtt"      yaml:"avgRtt"      toml:"avgRtt"`      // 平均RTT (ミリ秒)
+	MaxRTT      float64 `json:"maxRtt"      yaml:"maxRtt"      toml:"maxRtt"`      // 最大RTT (ミリ秒)
+	Latency     float64 `json:"latency"     yaml:"latency"     toml:"latency"`     // 監視の応答時間 (ミリ秒)
+}
+
 // Target is config of target
 type Target struct {
-	Protocol             string   `json:"protocol"       yaml:"protocol"       toml:"protocol"`       // プロトコル icmp, udp, udpRegexp, tcp, tcpRegexp, http, httpRegexp
+	Template              string            `json:"template"              yaml:"template"              toml:"template"`              // テンプレート名 テンプレートの値を引き継ぎ、指定したフィールドだけ上書きする
+	Protocol             string   `json:"protocol"       yaml:"protocol"       toml:"protocol"`       // プロトコル icmp, udp, udpRegexp, tcp, tcpRegexp, http, httpRegexp, httpJson, dns, tlsCert, exec, grpc, mysql, postgres
 	Dest                 string   `json:"dest"           yaml:"dest"           toml:"dest"`           // 宛先
 	TCPTLS               bool     `json:"tcpTls"         yaml:"tcpTls"         toml:"tcpTls"`         // TCPにTLSを使う
+	TCPStepList           []*TCPStep        `json:"tcpStepList"           yaml:"tcpStepList"           toml:"tcpStepList"`           // TCPで順に実行する送信と期待する応答のリスト
+	Payload               string            `json:"payload"               yaml:"payload"               toml:"payload"`               // UDPで送信するペイロード (エスケープ文字列)
+	PayloadHex            bool              `json:"payloadHex"            yaml:"payloadHex"            toml:"payloadHex"`            // ペイロードを16進数文字列として扱う
 	HTTPMethod           string   `json:"httpMethod"     yaml:"httpMethod"     toml:"httpMethod"`     // HTTPメソッド
 	HTTPStatusList       []string `json:"httpStatusList" yaml:"httpStatusList" toml:"httpStatusList"` // OKとみなすHTTPステータスコード
+	HTTPHeaderMap         map[string]string `json:"httpHeaderMap"         yaml:"httpHeaderMap"         toml:"httpHeaderMap"`         // HTTPで送信するヘッダー (Hostを含む)
+	HTTPBody              string            `json:"httpBody"              yaml:"httpBody"              toml:"httpBody"`              // HTTPで送信するボディ
+	HTTPRedirectPolicy    string            `json:"httpRedirectPolicy"    yaml:"httpRedirectPolicy"    toml:"httpRedirectPolicy"`    // HTTPのリダイレクトポリシー follow, none, sameHost
+	HTTPJSONAssertionList []*JSONAssertion  `json:"httpJsonAssertionList" yaml:"httpJsonAssertionList" toml:"httpJsonAssertionList"` // HTTPのJSONレスポンスに対するアサーションのリスト
+	DNSName               string            `json:"dnsName"               yaml:"dnsName"               toml:"dnsName"`               // DNSで問い合わせる名前
+	DNSQtype              string            `json:"dnsQtype"              yaml:"dnsQtype"              toml:"dnsQtype"`              // DNSで問い合わせるタイプ
+	DNSTransport          string            `json:"dnsTransport"          yaml:"dnsTransport"          toml:"dnsTransport"`          // DNSのトランスポート udp, tcp, tls
+	DNSRecursionDesired   bool              `json:"dnsRecursionDesired"   yaml:"dnsRecursionDesired"   toml:"dnsRecursionDesired"`   // DNSでRDビットを立てる
+	DNSRcodeList          []string          `json:"dnsRcodeList"          yaml:"dnsRcodeList"          toml:"dnsRcodeList"`          // OKとみなすDNSのRCODE
+	ExecArgList           []string          `json:"execArgList"           yaml:"execArgList"           toml:"execArgList"`           // execで実行するコマンドの引数 (コマンドはdestに指定)
+	ExecEnvMap            map[string]string `json:"execEnvMap"            yaml:"execEnvMap"            toml:"execEnvMap"`            // execで実行するコマンドに追加する環境変数
+	GRPCServiceName       string            `json:"grpcServiceName"       yaml:"grpcServiceName"       toml:"grpcServiceName"`       // gRPCのヘルスチェックで問い合わせるサービス名 (空の場合はサーバー全体)
+	GRPCTLS               bool              `json:"grpcTls"               yaml:"grpcTls"               toml:"grpcTls"`               // gRPCにTLSを使う
+	DBUser                string            `json:"dbUser"                yaml:"dbUser"                toml:"dbUser"`                // mysql, postgresで認証に使うユーザー名
+	DBPassword            string            `json:"dbPassword"            yaml:"dbPassword"            toml:"dbPassword"`            // mysql, postgresで認証に使うパスワード
+	DBName                string            `json:"dbName"                yaml:"dbName"                toml:"dbName"`                // mysql, postgresで接続するデータベース名
+	DBQuery               string            `json:"dbQuery"               yaml:"dbQuery"               toml:"dbQuery"`               // mysql, postgresで実行するクエリ (空の場合は接続のみ)
+	DBExpectedValue       string            `json:"dbExpectedValue"       yaml:"dbExpectedValue"       toml:"dbExpectedValue"`       // クエリが返す単一の値の期待値 (空の場合は比較しない)
+	DBTLS                 bool              `json:"dbTls"                 yaml:"dbTls"                 toml:"dbTls"`                 // mysql, postgresにTLSを使う
 	Regexp               string   `json:"regexp"         yaml:"regexp"         toml:"regexp"`         // OKとみなす正規表現  
 	ResSize              uint32   `json:"resSize"        yaml:"resSize"        toml:"resSize"`        // 受信する最大レスポンスサイズ   
+	ICMPCount             uint32            `json:"icmpCount"             yaml:"icmpCount"             toml:"icmpCount"`             // ICMPで一度に送信するエコーの数
+	ICMPInterval          uint32            `json:"icmpInterval"          yaml:"icmpInterval"          toml:"icmpInterval"`          // ICMPエコーの送信間隔 (ミリ秒)
+	ICMPMaxLoss           uint32            `json:"icmpMaxLoss"           yaml:"icmpMaxLoss"           toml:"icmpMaxLoss"`           // ダウンとみなすパケットロス率 (%) 0の場合は全てロスした場合のみ
+	ICMPMaxAvgRTT         uint32            `json:"icmpMaxAvgRtt"         yaml:"icmpMaxAvgRtt"         toml:"icmpMaxAvgRtt"`         // ダウンとみなす平均RTT (ミリ秒) 0の場合は判定しない
 	Retry                uint32   `json:"retry"          yaml:"retry"          toml:"retry"`          // リトライ回数 
 	RetryWait            uint32   `json:"retryWait"      yaml:"retryWait"      toml:"retryWait"`      // 次のリトライまでの待ち時間   
 	Timeout              uint32   `json:"timeout"        yaml:"timeout"        toml:"timeout"`        // タイムアウトしたとみなす時間  
 	TLSSkipVerify        bool     `json:"tlsSkipVerify"  yaml:"tlsSkipVerify"  toml:"tlsSkipVerify"`  // TLSの検証をスキップする 
+	TLSServerName         string            `json:"tlsServerName"         yaml:"tlsServerName"         toml:"tlsServerName"`         // TLSのサーバー名(SNI)
+	TLSCAFile             string            `json:"tlsCaFile"             yaml:"tlsCaFile"             toml:"tlsCaFile"`             // TLSの検証に使うCAバンドルファイルパス
+	TLSClientCertFile     string            `json:"tlsClientCertFile"     yaml:"tlsClientCertFile"     toml:"tlsClientCertFile"`     // TLSのクライアント証明書ファイルパス
+	TLSClientKeyFile      string            `json:"tlsClientKeyFile"      yaml:"tlsClientKeyFile"      toml:"tlsClientKeyFile"`      // TLSのクライアントプライベートキーファイルパス
+	TLSCertExpireDays     uint32            `json:"tlsCertExpireDays"     yaml:"tlsCertExpireDays"     toml:"tlsCertExpireDays"`     // 証明書の有効期限がこの日数以内ならダウンとみなす
 	WatchInterval        uint32   `json:"watchInterval"  yaml:"watchInterval"  toml:"watchInterval"`  // 監視する間隔
-	currentIntervalCount uint32                                                                       // 現在の時間                       [mutable]
+	WatchIntervalMsec     uint32            `json:"watchIntervalMsec"     yaml:"watchIntervalMsec"     toml:"watchIntervalMsec"`     // 監視する間隔 (ミリ秒) 指定した場合はwatchIntervalより優先
+	WatchJitter           uint32            `json:"watchJitter"           yaml:"watchJitter"           toml:"watchJitter"`           // 監視する時刻に加えるランダムな遅延の最大値 (ミリ秒)
+	RiseCount             uint32            `json:"riseCount"             yaml:"riseCount"             toml:"riseCount"`             // 生存とみなすまでに連続して成功する回数
+	FallCount             uint32            `json:"fallCount"             yaml:"fallCount"             toml:"fallCount"`             // ダウンとみなすまでに連続して失敗する回数
+	ParentTargetNameList  []string          `json:"parentTargetNameList"  yaml:"parentTargetNameList"  toml:"parentTargetNameList"`  // 親ターゲットのリスト 親がダウンしている間はダウンではなく到達不能とみなす
+	lastProbeTime         time.Time                                                                                                  // 最後に監視した時刻 [mutable]
+	missedDeadlineCount   uint32                                                                                                     // 予定時刻に監視できなかった回数 [mutable]
+	probeHistory          []*ProbeResult                                                                                             // 監視結果の履歴 (リングバッファ) [mutable]
+	probeHistoryPos       int                                                                                                        // 監視結果の履歴の次の書き込み位置 [mutable]
+	riseCounter           uint32                                                                                                     // 連続して成功した回数 [mutable]
+	fallCounter           uint32                                                                                                     // 連続して失敗した回数 [mutable]
 	progress             bool                                                                         // 監視中を示すフラグ               [mutable]
+	detail                string                                                                                                     // 最後の監視結果の詳細             [mutable]
+	metric                *TargetMetric                                                                                              // 最後の監視で計測した値 [mutable]
+	raw                   *Target                                                                                                    // テンプレートを解決する前のターゲット [mutable]
+	specifiedFieldMap     map[string]bool                                                                                            // 設定で指定されたフィールドのjson名 テンプレートの値で上書きしない [mutable]
+	unreachable           bool                                                                                                       // 親ターゲットがダウンしているため到達不能であることを示すフラグ [mutable]
+	rootCause             bool                                                                                                       // 子ターゲットが到達不能になった原因であることを示すフラグ [mutable]
+	tlsConfig             *tls.Config                                                                                                // 解析済みのTLS設定 (CAバンドルとクライアント証明書を監視のたびに読まないためのキャッシュ) [mutable]
 	alive                bool     `json:"alive"          yaml:"alive"          toml:"alive"`          // 生存フラグ                       [mutable]
 }
 
+// targetConfig is same as Target without methods, in order to decode without recursion
+type targetConfig Target
+
+// setSpecifiedFieldMap is set field names that are specified in config
+func (t *Target) setSpecifiedFieldMap(fieldMap map[string]interface{}) {
+	t.specifiedFieldMap = make(map[string]bool, len(fieldMap))
+	for fieldName := range fieldMap {
+		t.specifiedFieldMap[fieldName] = true
+	}
+}
+
+// SetSpecifiedFieldNameList is set json names of fields that are specified in request, these fields are not inherited from template
+func (t *Target) SetSpecifiedFieldNameList(fieldNameList []string) {
+	t.specifiedFieldMap = make(map[string]bool, len(fieldNameList))
+	for _, fieldName := range fieldNameList {
+		t.specifiedFieldMap[fieldName] = true
+	}
+}
+
+// UnmarshalJSON is decode target and remember specified fields
+func (t *Target) UnmarshalJSON(data []byte) (error) {
+	var fieldMap map[string]interface{}
+	if err := json.Unmarshal(data, &fieldMap); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, (*targetConfig)(t)); err != nil {
+		return err
+	}
+	t.setSpecifiedFieldMap(fieldMap)
+	return nil
+}
+
+// UnmarshalYAML is decode target and remember specified fields
+func (t *Target) UnmarshalYAML(unmarshal func(interface{}) error) (error) {
+	var fieldMap map[string]interface{}
+	if err := unmarshal(&fieldMap); err != nil {
+		return err
+	}
+	if err := unmarshal((*targetConfig)(t)); err != nil {
+		return err
+	}
+	t.setSpecifiedFieldMap(fieldMap)
+	return nil
+}
+
+// UnmarshalTOML is decode target and remember specified fields
+func (t *Target) UnmarshalTOML(data interface{}) (error) {
+	fieldMap, ok := data.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("unexpected type of target (%T)", data)
+	}
+	// toml keys are same as json keys
+	buf, err := json.Marshal(fieldMap)
+	if err != nil {
+		return errors.Wrap(err, "can not encode target")
+	}
+	if err := json.Unmarshal(buf, (*targetConfig)(t)); err != nil {
+		return errors.Wrap(err, "can not decode target")
+	}
+	t.setSpecifiedFieldMap(fieldMap)
+	return nil
+}
+
+// isRawConfig is whether target is config before resolving template, such target has only specified fields
+func (t *Target) isRawConfig() (bool) {
+	return t.Template != "" && t.raw == nil && t.specifiedFieldMap != nil
+}
+
+// specifiedConfig is get specified fields of target
+func (t *Target) specifiedConfig() (map[string]interface{}, error) {
+	buf, err := json.Marshal((*targetConfig)(t))
+	if err != nil {
+		return nil, errors.Wrap(err, "can not encode target")
+	}
+	var fieldMap map[string]interface{}
+	if err := json.Unmarshal(buf, &fieldMap); err != nil {
+		return nil, errors.Wrap(err, "can not decode target")
+	}
+	for fieldName := range fieldMap {
+		if !t.specifiedFieldMap[fieldName] {
+			delete(fieldMap, fieldName)
+		}
+	}
+	return fieldMap, nil
+}
+
+// MarshalJSON is encode target, raw config is encoded with only specified fields in order to keep inheritance from template
+func (t *Target) MarshalJSON() ([]byte, error) {
+	if !t.isRawConfig() {
+		return json.Marshal((*targetConfig)(t))
+	}
+	fieldMap, err := t.specifiedConfig()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fieldMap)
+}
+
+// MarshalYAML is encode target, raw config is encoded with only specified fields in order to keep inheritance from template
+func (t *Target) MarshalYAML() (interface{}, error) {
+	if !t.isRawConfig() {
+		return (*targetConfig)(t), nil
+	}
+	return t.specifiedConfig()
+}
+
+// MarshalTOML is encode target as inline table, raw config is encoded with only specified fields in order to keep inheritance from template
+func (t *Target) MarshalTOML() ([]byte, error) {
+	var fieldMap map[string]interface{}
+	if t.isRawConfig() {
+		specifiedFieldMap, err := t.specifiedConfig()
+		if err != nil {
+			return nil, err
+		}
+		fieldMap = specifiedFieldMap
+	} else {
+		buf, err := json.Marshal((*targetConfig)(t))
+		if err != nil {
+			return nil, errors.Wrap(err, "can not encode target")
+		}
+		if err := json.Unmarshal(buf, &fieldMap); err != nil {
+			return nil, errors.Wrap(err, "can not decode target")
+		}
+	}
+	var buffer bytes.Buffer
+	if err := writeTOMLValue(&buffer, fieldMap); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// writeTOMLValue is write value decoded from json as toml inline value
+func writeTOMLValue(buffer *bytes.Buffer, value interface{}) (error) {
+	switch v := value.(type) {
+	case bool:
+		buffer.WriteString(strconv.FormatBool(v))
+	case float64:
+		// integer must not be written in exponent form
+		buffer.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	case string:
+		// escapes of json string are valid in toml basic string
+		quoted, err := json.Marshal(v)
+		if err != nil {
+			return errors.Wrap(err, "can not quote string")
+		}
+		buffer.Write(quoted)
+	case []interface{}:
+		buffer.WriteString("[")
+		for i, element := range v {
+			if i > 0 {
+				buffer.WriteString(", ")
+			}
+			if err := writeTOMLValue(buffer, element); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString("]")
+	case map[string]interface{}:
+		keyList := make([]string, 0, len(v))
+		for key, element := range v {
+			if element == nil {
+				// toml has no null, absent key is decoded as zero value
+				continue
+			}
+			keyList = append(keyList, key)
+		}
+		sort.Strings(keyList)
+		buffer.WriteString("{")
+		for i, key := range keyList {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			quoted, err := json.Marshal(key)
+			if err != nil {
+				return errors.Wrap(err, "can not quote key")
+			}
+			buffer.WriteString(" ")
+			buffer.Write(quoted)
+			buffer.WriteString(" = ")
+			if err := writeTOMLValue(buffer, v[key]); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString(" }")
+	default:
+		return errors.Errorf("unexpected type of value (%T)", value)
+	}
+	return nil
+}
+
+// copyTargetConfig is copy config fields, when specifiedFieldMap is not nil, skip fields specified in dst
+// when specifiedFieldMap is nil and onlyZero is true, copy only to zero fields
+func copyTargetConfig(dst *Target, src *Target, onlyZero bool) {
+	dstValue := reflect.ValueOf(dst).Elem()
+	srcValue := reflect.ValueOf(src).Elem()
+	targetType := dstValue.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		if targetType.Field(i).PkgPath != "" {
+			// unexported field is not config
+			continue
+		}
+		field := dstValue.Field(i)
+		if onlyZero {
+			if dst.specifiedFieldMap != nil {
+				fieldName := strings.Split(targetType.Field(i).Tag.Get("json"), ",")[0]
+				if dst.specifiedFieldMap[fieldName] {
+					continue
+				}
+			} else if !field.IsZero() {
+				// target is not decoded from config, so specified fields are unknown
+				continue
+			}
+		}
+		field.Set(srcValue.Field(i))
+	}
+}
+
+func (t *Target) resolveTemplate(templateMap map[string]*Target) (bool) {
+	if t.raw != nil {
+		// already resolved, resolve again from raw config
+		copyTargetConfig(t, t.raw, false)
+	}
+	if t.Template == "" {
+		t.raw = nil
+		return true
+	}
+	template, ok := templateMap[t.Template]
+	if !ok {
+		belog.Error("unknown template (%v)", t.Template)
+		return false
+	}
+	raw := new(Target)
+	copyTargetConfig(raw, t, false)
+	raw.specifiedFieldMap = t.specifiedFieldMap
+	t.raw = raw
+	copyTargetConfig(t, template, true)
+	// keep template name of target
+	t.Template = raw.Template
+	return true
+}
+
+// GetRaw is get target before resolving template
+func (t *Target) GetRaw() (*Target) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	if t.raw == nil {
+		return t
+	}
+	return t.raw
+}
+
 func (t *Target) validate() (bool) {
-	if t.Protocol == "" || t.Dest == "" || t.WatchInterval == 0 {
+	if t.Protocol == "" || t.Dest == "" || (t.WatchInterval == 0 && t.WatchIntervalMsec == 0) {
 		belog.Error("no name or no protocol or no dest")
 		return false
 	}
-	if t.Protocol == "http" || t.Protocol == "httpRegexp" {
+	if t.Protocol == "http" || t.Protocol == "httpRegexp" || t.Protocol == "httpJson" {
 		if t.HTTPMethod == "" || t.HTTPStatusList == nil || len(t.HTTPStatusList) == 0 {
 			belog.Error("no httpMethod or no httpStatusList")
 			return false
 		}
 	}
+	if t.Protocol == "httpJson" {
+		if t.HTTPJSONAssertionList == nil || len(t.HTTPJSONAssertionList) == 0 {
+			belog.Error("no httpJsonAssertionList")
+			return false
+		}
+		for _, jsonAssertion := range t.HTTPJSONAssertionList {
+			if !jsonAssertion.validate() {
+				return false
+			}
+		}
+	}
+	if t.HTTPRedirectPolicy != "" {
+		redirectPolicy := strings.ToUpper(t.HTTPRedirectPolicy)
+		if redirectPolicy != "FOLLOW" && redirectPolicy != "NONE" && redirectPolicy != "SAMEHOST" {
+			belog.Error("unexpected httpRedirectPolicy")
+			return false
+		}
+	}
+	if t.Protocol == "dns" {
+		if t.DNSName == "" {
+			belog.Error("no dnsName")
+			return false
+		}
+	}
+	if t.Protocol == "mysql" || t.Protocol == "postgres" {
+		if t.DBUser == "" {
+			belog.Error("no dbUser")
+			return false
+		}
+	}
+	if t.Protocol == "postgres" && t.TLSServerName != "" {
+		// lib/pq has no option of server name, it always verifies host of dest
+		belog.Error("tlsServerName is not supported with postgres")
+		return false
+	}
+	if t.TCPStepList != nil {
+		for _, tcpStep := range t.TCPStepList {
+			if !tcpStep.validate() {
+				return false
+			}
+		}
+	}
 	return true
 }
 
-// GetCurrentIntervalCount is get currentIntervalCount
-func (t *Target) GetCurrentIntervalCount() (uint32) {
+// GetInterval is get watch interval
+func (t *Target) GetInterval() (time.Duration) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	if t.WatchIntervalMsec > 0 {
+		return time.Duration(t.WatchIntervalMsec) * time.Millisecond
+	}
+	return time.Duration(t.WatchInterval) * time.Second
+}
+
+// SetLastProbeTime is set lastProbeTime
+func (t *Target) SetLastProbeTime(lastProbeTime time.Time) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	t.lastProbeTime = lastProbeTime
+}
+
+// GetLastProbeTime is get lastProbeTime
+func (t *Target) GetLastProbeTime() (time.Time) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	return t.lastProbeTime
+}
+
+// IncrementMissedDeadlineCount is increment missedDeadlineCount
+func (t *Target) IncrementMissedDeadlineCount() {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	t.missedDeadlineCount++
+}
+
+// GetMissedDeadlineCount is get missedDeadlineCount
+func (t *Target) GetMissedDeadlineCount() (uint32) {
 	mutableMutex.Lock()
 	defer mutableMutex.Unlock()
-	return t.currentIntervalCount
+	return t.missedDeadlineCount
 }
 
-// IncrementCurrentIntervalCount is increment currentIntervalCount
-func (t *Target) IncrementCurrentIntervalCount() {
+// GetRiseCounter is get riseCounter
+func (t *Target) GetRiseCounter() (uint32) {
 	mutableMutex.Lock()
 	defer mutableMutex.Unlock()
-	t.currentIntervalCount++
+	return t.riseCounter
 }
 
-// ClearCurrentIntervalCount is clear currentIntervalCount
-func (t *Target) ClearCurrentIntervalCount() {
+// GetFallCounter is get fallCounter
+func (t *Target) GetFallCounter() (uint32) {
 	mutableMutex.Lock()
 	defer mutableMutex.Unlock()
-	t.currentIntervalCount = 0
+	return t.fallCounter
 }
 
 // SetProgress is set progress
@@ -759,6 +1380,13 @@ func (t *Target) SetProgress(progress bool) {
 	t.progress = progress
 }
 
+// GetProgress is get progress
+func (t *Target) GetProgress() (bool) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	return t.progress
+}
+
 // CompareAndSwapProgress is set progress
 func (t *Target) CompareAndSwapProgress(oldProgress bool, newProgress bool) (bool) {
 	mutableMutex.Lock()
@@ -777,6 +1405,102 @@ func (t *Target) SetAlive(alive bool) {
 	t.alive = alive
 }
 
+// ProbeResult is result of probe
+type ProbeResult struct {
+	Time     time.Time `json:"time"`     // 監視した時刻
+	Duration float64   `json:"duration"` // 監視にかかった時間 (ミリ秒)
+	Success  bool      `json:"success"`  // 監視の結果
+	Error    string    `json:"error"`    // 失敗した場合の詳細
+}
+
+// AddProbeResult is add probe result to history
+func (t *Target) AddProbeResult(probeResult *ProbeResult) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	if t.probeHistory == nil {
+		t.probeHistory = make([]*ProbeResult, 0, historySize)
+	}
+	if len(t.probeHistory) < historySize {
+		t.probeHistory = append(t.probeHistory, probeResult)
+	} else {
+		t.probeHistory[t.probeHistoryPos] = probeResult
+	}
+	t.probeHistoryPos = (t.probeHistoryPos + 1) % historySize
+}
+
+// GetProbeHistory is get probe history in chronological order
+func (t *Target) GetProbeHistory() ([]*ProbeResult) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	history := make([]*ProbeResult, 0, len(t.probeHistory))
+	if len(t.probeHistory) < historySize {
+		return append(history, t.probeHistory...)
+	}
+	history = append(history, t.probeHistory[t.probeHistoryPos:]...)
+	return append(history, t.probeHistory[:t.probeHistoryPos]...)
+}
+
+// TargetState is runtime state of target
+type TargetState struct {
+	LastProbeTime        time.Time     `json:"lastProbeTime"`        // 最後に監視した時刻
+	RiseCounter          uint32        `json:"riseCounter"`          // 連続して成功した回数
+	FallCounter          uint32        `json:"fallCounter"`          // 連続して失敗した回数
+	Detail               string        `json:"detail"`               // 最後の監視結果の詳細
+	Metric               *TargetMetric `json:"metric"`               // 最後の監視で計測した値
+	Alive                bool          `json:"alive"`                // 生存フラグ
+}
+
+// GetState is get runtime state
+func (t *Target) GetState() (*TargetState) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	var metric *TargetMetric
+	if t.metric != nil {
+		m := *t.metric
+		metric = &m
+	}
+	return &TargetState{
+		LastProbeTime:        t.lastProbeTime,
+		RiseCounter:          t.riseCounter,
+		FallCounter:          t.fallCounter,
+		Detail:               t.detail,
+		Metric:               metric,
+		Alive:                t.alive,
+	}
+}
+
+// SetState is set runtime state
+func (t *Target) SetState(state *TargetState) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	t.lastProbeTime = state.LastProbeTime
+	t.riseCounter = state.RiseCounter
+	t.fallCounter = state.FallCounter
+	t.detail = state.Detail
+	t.metric = state.Metric
+	t.alive = state.Alive
+}
+
+// UpdateAlive is update alive by probe result with riseCount and fallCount
+func (t *Target) UpdateAlive(probeAlive bool) (bool) {
+	mutableMutex.Lock()
+        defer mutableMutex.Unlock()
+	if probeAlive {
+		t.riseCounter++
+		t.fallCounter = 0
+		if !t.alive && t.riseCounter >= t.RiseCount {
+			t.alive = true
+		}
+	} else {
+		t.fallCounter++
+		t.riseCounter = 0
+		if t.alive && t.fallCounter >= t.FallCount {
+			t.alive = false
+		}
+	}
+	return t.alive
+}
+
 // GetAlive is get alive
 func (t *Target) GetAlive() (bool) {
 	mutableMutex.Lock()
@@ -784,21 +1508,136 @@ func (t *Target) GetAlive() (bool) {
 	return t.alive
 }
 
+// SetUnreachable is set unreachable
+func (t *Target) SetUnreachable(unreachable bool) {
+	mutableMutex.Lock()
+        defer mutableMutex.Unlock()
+	t.unreachable = unreachable
+}
+
+// GetUnreachable is get unreachable
+func (t *Target) GetUnreachable() (bool) {
+	mutableMutex.Lock()
+        defer mutableMutex.Unlock()
+	return t.unreachable
+}
+
+// SwapRootCause is swap root cause
+func (t *Target) SwapRootCause(rootCause bool) (bool) {
+	mutableMutex.Lock()
+        defer mutableMutex.Unlock()
+	oldRootCause := t.rootCause
+	t.rootCause = rootCause
+	return oldRootCause
+}
+
+// GetTLSConfig is get cached tls config, newTLSConfig is called to build it when not cached
+func (t *Target) GetTLSConfig(newTLSConfig func() (*tls.Config, error)) (*tls.Config, error) {
+	mutableMutex.Lock()
+	tlsConfig := t.tlsConfig
+	mutableMutex.Unlock()
+	if tlsConfig != nil {
+		return tlsConfig, nil
+	}
+	tlsConfig, err := newTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	mutableMutex.Lock()
+        defer mutableMutex.Unlock()
+	t.tlsConfig = tlsConfig
+	return tlsConfig, nil
+}
+
+// SetDetail is set detail
+func (t *Target) SetDetail(detail string) {
+	mutableMutex.Lock()
+        defer mutableMutex.Unlock()
+	t.detail = detail
+}
+
+// GetDetail is get detail
+func (t *Target) GetDetail() (string) {
+	mutableMutex.Lock()
+        defer mutableMutex.Unlock()
+	return t.detail
+}
+
+// SetMetric is set metric
+func (t *Target) SetMetric(metric *TargetMetric) {
+	mutableMutex.Lock()
+        defer mutableMutex.Unlock()
+	t.metric = metric
+}
+
+// GetMetric is get metric
+func (t *Target) GetMetric() (*TargetMetric) {
+	mutableMutex.Lock()
+        defer mutableMutex.Unlock()
+	if t.metric == nil {
+		return nil
+	}
+	metric := *t.metric
+	return &metric
+}
+
 // Update is update target 
 func (t *Target) Update(newTarget *Target)  {
 	mutableMutex.Lock()
         defer mutableMutex.Unlock()
+	t.Template = newTarget.Template
+	t.raw = newTarget.raw
+	t.specifiedFieldMap = newTarget.specifiedFieldMap
 	t.Protocol = newTarget.Protocol
 	t.Dest = newTarget.Dest
 	t.TCPTLS = newTarget.TCPTLS
+	t.TCPStepList = newTarget.TCPStepList
+	t.Payload = newTarget.Payload
+	t.PayloadHex = newTarget.PayloadHex
 	t.HTTPMethod = newTarget.HTTPMethod
 	t.HTTPStatusList = newTarget.HTTPStatusList
+	t.HTTPHeaderMap = newTarget.HTTPHeaderMap
+	t.HTTPBody = newTarget.HTTPBody
+	t.HTTPRedirectPolicy = newTarget.HTTPRedirectPolicy
+	t.HTTPJSONAssertionList = newTarget.HTTPJSONAssertionList
+	t.DNSName = newTarget.DNSName
+	t.DNSQtype = newTarget.DNSQtype
+	t.DNSTransport = newTarget.DNSTransport
+	t.DNSRecursionDesired = newTarget.DNSRecursionDesired
+	t.DNSRcodeList = newTarget.DNSRcodeList
+	t.ExecArgList = newTarget.ExecArgList
+	t.ExecEnvMap = newTarget.ExecEnvMap
+	t.GRPCServiceName = newTarget.GRPCServiceName
+	t.GRPCTLS = newTarget.GRPCTLS
+	t.DBUser = newTarget.DBUser
+	t.DBPassword = newTarget.DBPassword
+	t.DBName = newTarget.DBName
+	t.DBQuery = newTarget.DBQuery
+	t.DBExpectedValue = newTarget.DBExpectedValue
+	t.DBTLS = newTarget.DBTLS
 	t.Regexp = newTarget.Regexp
 	t.ResSize = newTarget.ResSize
+	t.ICMPCount = newTarget.ICMPCount
+	t.ICMPInterval = newTarget.ICMPInterval
+	t.ICMPMaxLoss = newTarget.ICMPMaxLoss
+	t.ICMPMaxAvgRTT = newTarget.ICMPMaxAvgRTT
 	t.Retry = newTarget.Retry
 	t.RetryWait = newTarget.RetryWait
 	t.Timeout = newTarget.Timeout
 	t.TLSSkipVerify = newTarget.TLSSkipVerify
+	t.TLSServerName = newTarget.TLSServerName
+	t.TLSCAFile = newTarget.TLSCAFile
+	t.TLSClientCertFile = newTarget.TLSClientCertFile
+	t.TLSClientKeyFile = newTarget.TLSClientKeyFile
+	t.TLSCertExpireDays = newTarget.TLSCertExpireDays
+	t.RiseCount = newTarget.RiseCount
+	t.WatchInterval = newTarget.WatchInterval
+	t.WatchIntervalMsec = newTarget.WatchIntervalMsec
+	t.WatchJitter = newTarget.WatchJitter
+	t.FallCount = newTarget.FallCount
+	t.ParentTargetNameList = newTarget.ParentTargetNameList
+	// tls options may be changed
+	t.tlsConfig = nil
 }
 
 // TargetName is target name
@@ -817,10 +1656,195 @@ func (t TargetName) String() (string) {
         return string(t)
 }
 
+// Maintenance is maintenance window
+type Maintenance struct {
+	Start         time.Time `json:"start"         yaml:"start"         toml:"start"`         // 一回限りのメンテナンスの開始時刻
+	End           time.Time `json:"end"           yaml:"end"           toml:"end"`           // 一回限りのメンテナンスの終了時刻
+	Cron          string    `json:"cron"          yaml:"cron"          toml:"cron"`          // 繰り返すメンテナンスの開始時刻 (cron形式 "分 時 日 月 曜日") example: "0 3 * * 0"
+	Duration      uint32    `json:"duration"      yaml:"duration"      toml:"duration"`      // 繰り返すメンテナンスの期間 (秒)
+	TargetName    string    `json:"targetName"    yaml:"targetName"    toml:"targetName"`    // 対象のターゲット このターゲットを参照する動的レコードが対象になる
+	Domain        string    `json:"domain"        yaml:"domain"        toml:"domain"`        // 対象のゾーン
+	GroupName     string    `json:"groupName"     yaml:"groupName"     toml:"groupName"`     // 対象の動的グループ 空の場合はゾーン全体
+	RecordName    string    `json:"recordName"    yaml:"recordName"    toml:"recordName"`    // 対象の動的レコード名 空の場合はグループ全体
+	RecordType    string    `json:"recordType"    yaml:"recordType"    toml:"recordType"`    // 対象の動的レコードタイプ 空の場合は全てのタイプ
+	RecordContent string    `json:"recordContent" yaml:"recordContent" toml:"recordContent"` // 対象の動的レコード内容 空の場合は全ての内容
+	Comment       string    `json:"comment"       yaml:"comment"       toml:"comment"`       // コメント
+	cronSchedule  *helper.CronSchedule                                                       // パースしたcron [mutable]
+	active        bool                                                                       // メンテナンス中フラグ [mutable]
+}
+
+func (m *Maintenance) validate() (bool) {
+	if m.Cron != "" {
+		if !m.Start.IsZero() || !m.End.IsZero() {
+			belog.Error("both cron and start/end are specified")
+			return false
+		}
+		if m.Duration == 0 || m.Duration > maxMaintenanceDuration {
+			belog.Error("invalid duration (%v), must be 1 - %v", m.Duration, maxMaintenanceDuration)
+			return false
+		}
+		cronSchedule, err := helper.ParseCron(m.Cron)
+		if err != nil {
+			belog.Error("%v", err)
+			return false
+		}
+		m.cronSchedule = cronSchedule
+	} else {
+		if m.Start.IsZero() || m.End.IsZero() || !m.End.After(m.Start) {
+			belog.Error("no cron or invalid start/end")
+			return false
+		}
+	}
+	if m.TargetName != "" {
+		if m.Domain != "" || m.GroupName != "" || m.RecordName != "" || m.RecordType != "" || m.RecordContent != "" {
+			belog.Error("both targetName and domain/groupName/record are specified")
+			return false
+		}
+		return true
+	}
+	if m.Domain == "" {
+		belog.Error("no targetName or no domain")
+		return false
+	}
+	if m.GroupName == "" && (m.RecordName != "" || m.RecordType != "" || m.RecordContent != "") {
+		belog.Error("no groupName")
+		return false
+	}
+	return true
+}
+
+// Match is whether dynamic record is in scope of maintenance
+func (m *Maintenance) Match(domain string, groupName string, record *DynamicRecord) (bool) {
+	if m.TargetName != "" {
+		for _, targetName := range record.TargetNameList {
+			if targetName == m.TargetName {
+				return true
+			}
+		}
+		return false
+	}
+	if m.Domain != domain {
+		return false
+	}
+	if m.GroupName != "" && m.GroupName != groupName {
+		return false
+	}
+	if m.RecordName != "" && m.RecordName != record.Name {
+		return false
+	}
+	if m.RecordType != "" && !strings.EqualFold(m.RecordType, record.Type) {
+		return false
+	}
+	if m.RecordContent != "" && m.RecordContent != record.Content {
+		return false
+	}
+	return true
+}
+
+// UpdateActive is update whether maintenance is active at now, return active and whether it changed
+func (m *Maintenance) UpdateActive(now time.Time) (bool, bool) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	var active bool
+	if m.cronSchedule != nil {
+		active = !m.cronSchedule.LastStart(now, time.Duration(m.Duration) * time.Second).IsZero()
+	} else {
+		active = !now.Before(m.Start) && now.Before(m.End)
+	}
+	changed := active != m.active
+	m.active = active
+	return active, changed
+}
+
+// GetActive is get whether maintenance is active
+func (m *Maintenance) GetActive() (bool) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	return m.active
+}
+
+// String is string
+func (m *Maintenance) String() (string) {
+	var scope string
+	if m.TargetName != "" {
+		scope = fmt.Sprintf("target %v", m.TargetName)
+	} else {
+		scope = strings.TrimSpace(fmt.Sprintf("zone %v %v %v %v %v", m.Domain, m.GroupName, m.RecordName, m.RecordType, m.RecordContent))
+	}
+	if m.Cron != "" {
+		return fmt.Sprintf("%v (cron %v, duration %vs)", scope, m.Cron, m.Duration)
+	}
+	return fmt.Sprintf("%v (%v - %v)", scope, m.Start.Format(time.RFC3339), m.End.Format(time.RFC3339))
+}
+
+// findTargetDependencyCycle is find cycle in dependency of targets, return target name list of cycle
+func findTargetDependencyCycle(targetMap map[string]*Target) ([]string) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	stateMap := make(map[string]int, len(targetMap))
+	path := make([]string, 0)
+	var visit func(targetName string) ([]string)
+	visit = func(targetName string) ([]string) {
+		switch stateMap[targetName] {
+		case visiting:
+			for i, name := range path {
+				if name == targetName {
+					return append(append([]string{}, path[i:]...), targetName)
+				}
+			}
+			return []string{ targetName }
+		case visited:
+			return nil
+		}
+		stateMap[targetName] = visiting
+		path = append(path, targetName)
+		if target, ok := targetMap[targetName]; ok {
+			for _, parentTargetName := range target.ParentTargetNameList {
+				if cycle := visit(parentTargetName); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path) - 1]
+		stateMap[targetName] = visited
+		return nil
+	}
+	for targetName := range targetMap {
+		if cycle := visit(targetName); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+func validateTargetDependency(targetMap map[string]*Target) (bool) {
+	for targetName, target := range targetMap {
+		for _, parentTargetName := range target.ParentTargetNameList {
+			if _, ok := targetMap[parentTargetName]; !ok {
+				belog.Error("unknown parent target (%v) of target (%v)", parentTargetName, targetName)
+				return false
+			}
+		}
+	}
+	if cycle := findTargetDependencyCycle(targetMap); cycle != nil {
+		belog.Error("cycle in dependency of targets (%v)", strings.Join(cycle, " -> "))
+		return false
+	}
+	return true
+}
+
 // Watcher is watcher
 type Watcher struct {
 	ZoneMap       map[string]*Zone       `json:"zoneMap"      yaml:"zoneMap"        toml:"zoneMap"`       // ゾーン [mutable]
 	TargetMap     map[string]*Target `json:"targetMap"    yaml:"targetMap"      toml:"targetMap"`     // ゾーン [mutable]
+	TargetTemplateMap map[string]*Target      `json:"targetTemplateMap" yaml:"targetTemplateMap" toml:"targetTemplateMap"` // ターゲットのテンプレート
+	MaintenanceMap    map[string]*Maintenance `json:"maintenanceMap"    yaml:"maintenanceMap"    toml:"maintenanceMap"`    // メンテナンス [mutable]
+	StatePath         string                  `json:"statePath"         yaml:"statePath"         toml:"statePath"`         // 実行時の状態を保存するファイルのパス 空の場合は保存しない
+	StateSaveInterval uint32                  `json:"stateSaveInterval" yaml:"stateSaveInterval" toml:"stateSaveInterval"` // 実行時の状態を保存する間隔 (秒)
+	WorkerCount       uint32                  `json:"workerCount"       yaml:"workerCount"       toml:"workerCount"`       // 監視を並行して実行するワーカーの数
 	NotifySubject string                 `json:"notifySybject" yaml:"notifySybject" toml:"notifySybject"` // Notifyの題名テンプレート 
 	NotifyBody    string                 `json:"notifyBody"    yaml:"notifyBody"    toml:"notifyBody"`    // Notifyの本文テンプレート
 }
@@ -837,16 +1861,65 @@ func (w *Watcher) validate() (bool) {
 			}
 		}
 	}
+	if w.TargetTemplateMap != nil {
+		for templateName, template := range w.TargetTemplateMap {
+			if templateName == "" {
+				belog.Error("invalid template name")
+				return false
+			}
+			if template.Template != "" {
+				belog.Error("template (%v) can not refer other template", templateName)
+				return false
+			}
+		}
+	}
 	if w.TargetMap != nil {
 		for targetName, target := range w.TargetMap {
 			if targetName == "" {
 				return false
 			}
+			if !target.resolveTemplate(w.TargetTemplateMap) {
+				belog.Error("can not resolve template of target (%v)", targetName)
+				return false
+			}
 			if !target.validate() {
 				return false
 			}
 		}
 	}
+	if w.TargetMap != nil && !validateTargetDependency(w.TargetMap) {
+		return false
+	}
+	if w.MaintenanceMap != nil {
+		for maintenanceName, maintenance := range w.MaintenanceMap {
+			if maintenanceName == "" {
+				belog.Error("invalid maintenance name")
+				return false
+			}
+			if !maintenance.validate() {
+				return false
+			}
+			if maintenance.TargetName != "" {
+				if _, ok := w.TargetMap[maintenance.TargetName]; !ok {
+					belog.Error("unknown target (%v) in maintenance (%v)", maintenance.TargetName, maintenanceName)
+					return false
+				}
+			}
+		}
+	}
+	for domain, zone := range w.ZoneMap {
+		for groupName, dynamicGroup := range zone.DynamicGroupMap {
+			for _, dynamicRecord := range dynamicGroup.DynamicRecordList {
+				for _, targetName := range dynamicRecord.TargetNameList {
+					if _, ok := w.TargetMap[targetName]; !ok {
+						belog.Error("unknown target (%v) in targetNameList of dynamic record (%v %v %v %v %v)",
+							targetName, domain, groupName, dynamicRecord.Name, dynamicRecord.Type, dynamicRecord.Content)
+						return false
+					}
+				}
+			}
+		}
+	}
 	return true
 }
 
@@ -946,7 +2019,7 @@ func (w *Watcher) GetTarget(targetName string) (*Target, error) {
 func (w *Watcher) AddTarget(targetName string, target *Target) (error) {
 	mutableMutex.Lock()
 	defer mutableMutex.Unlock()
-	if targetName == "" || !target.validate() {
+	if targetName == "" || !target.resolveTemplate(w.TargetTemplateMap) || !target.validate() {
 		return errors.Errorf("invalid zone")
 	}
 	if w.TargetMap == nil {
@@ -956,10 +2029,40 @@ func (w *Watcher) AddTarget(targetName string, target *Target) (error) {
 	if ok {
 		return errors.Errorf("already exist domain")
 	}
+	for _, parentTargetName := range target.ParentTargetNameList {
+		if _, ok := w.TargetMap[parentTargetName]; !ok {
+			return errors.Errorf("not exist parent target (%v)", parentTargetName)
+		}
+	}
 	w.TargetMap[targetName] = target
 	return nil
 }
 
+// ResolveTargetTemplate is resolve template of target
+func (w *Watcher) ResolveTargetTemplate(target *Target) (error) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	if !target.resolveTemplate(w.TargetTemplateMap) || !target.validate() {
+		return errors.Errorf("invalid target")
+	}
+	return nil
+}
+
+// CheckTargetDependency is check dependency of targets when target is replaced with new target
+func (w *Watcher) CheckTargetDependency(targetName string, newTarget *Target) (error) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	targetMap := make(map[string]*Target, len(w.TargetMap) + 1)
+	for tn, t := range w.TargetMap {
+		targetMap[tn] = t
+	}
+	targetMap[targetName] = newTarget
+	if !validateTargetDependency(targetMap) {
+		return errors.Errorf("invalid dependency of target (%v)", targetName)
+	}
+	return nil
+}
+
 // DeleteTarget is delete target
 func (w *Watcher) DeleteTarget(targetName string) (error) {
 	mutableMutex.Lock()
@@ -971,10 +2074,95 @@ func (w *Watcher) DeleteTarget(targetName string) (error) {
 	if !ok {
 		return errors.Errorf("not exist domain")
 	}
+	for tn, t := range w.TargetMap {
+		for _, parentTargetName := range t.ParentTargetNameList {
+			if parentTargetName == targetName {
+				return errors.Errorf("target is parent of target (%v)", tn)
+			}
+		}
+	}
+	for domain, zone := range w.ZoneMap {
+		for groupName, dynamicGroup := range zone.DynamicGroupMap {
+			for _, dynamicRecord := range dynamicGroup.DynamicRecordList {
+				for _, tn := range dynamicRecord.TargetNameList {
+					if tn == targetName {
+						return errors.Errorf("target is referred by dynamic record (%v %v %v %v %v)",
+							domain, groupName, dynamicRecord.Name, dynamicRecord.Type, dynamicRecord.Content)
+					}
+				}
+			}
+		}
+	}
+	for maintenanceName, maintenance := range w.MaintenanceMap {
+		if maintenance.TargetName == targetName {
+			return errors.Errorf("target is referred by maintenance (%v)", maintenanceName)
+		}
+	}
 	delete(w.TargetMap, targetName)
 	return nil
 }
 
+// GetMaintenanceNameList is get maintenance name list
+func (w *Watcher) GetMaintenanceNameList() ([]string) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	if w.MaintenanceMap == nil {
+		w.MaintenanceMap = make(map[string]*Maintenance)
+	}
+	maintenanceNameList := make([]string, 0, len(w.MaintenanceMap))
+	for mn := range w.MaintenanceMap {
+		maintenanceNameList = append(maintenanceNameList, mn)
+	}
+	return maintenanceNameList
+}
+
+// GetMaintenance is get maintenance
+func (w *Watcher) GetMaintenance(maintenanceName string) (*Maintenance, error) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	if w.MaintenanceMap == nil {
+		w.MaintenanceMap = make(map[string]*Maintenance)
+	}
+	maintenance, ok := w.MaintenanceMap[maintenanceName]
+	if !ok {
+		return nil, errors.Errorf("not exist maintenance")
+	}
+	return maintenance, nil
+}
+
+// AddMaintenance is add maintenance
+func (w *Watcher) AddMaintenance(maintenanceName string, maintenance *Maintenance) (error) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	if maintenanceName == "" || !maintenance.validate() {
+		return errors.Errorf("invalid maintenance")
+	}
+	if w.MaintenanceMap == nil {
+		w.MaintenanceMap = make(map[string]*Maintenance)
+	}
+	_, ok := w.MaintenanceMap[maintenanceName]
+	if ok {
+		return errors.Errorf("already exist maintenance")
+	}
+	w.MaintenanceMap[maintenanceName] = maintenance
+	return nil
+}
+
+// DeleteMaintenance is delete maintenance
+func (w *Watcher) DeleteMaintenance(maintenanceName string) (error) {
+	mutableMutex.Lock()
+	defer mutableMutex.Unlock()
+	if w.MaintenanceMap == nil {
+		w.MaintenanceMap = make(map[string]*Maintenance)
+	}
+	_, ok := w.MaintenanceMap[maintenanceName]
+	if !ok {
+		return errors.Errorf("not exist maintenance")
+	}
+	delete(w.MaintenanceMap, maintenanceName)
+	return nil
+}
+
 // Mail is Mail
 type Mail struct {
 	HostPort      string `json:"hostPort"      yaml:"hostPort"      toml:"hostPort"`      // smtp接続先ホストとポート
@@ -996,9 +2184,136 @@ func (m *Mail) validate() (bool) {
 	return true
 }
 
+// Webhook is Webhook
+type Webhook struct {
+	URL               string            `json:"url"               yaml:"url"               toml:"url"`               // 送信先url
+	Method            string            `json:"method"            yaml:"method"            toml:"method"`            // HTTPメソッド 空の場合はPOST
+	HeaderMap         map[string]string `json:"headerMap"         yaml:"headerMap"         toml:"headerMap"`         // 送信するヘッダー
+	Body              string            `json:"body"              yaml:"body"              toml:"body"`              // ボディテンプレート 空の場合はsubjectとbodyを含むjson
+	TLSSkipVerify     bool              `json:"tlsSkipVerify"     yaml:"tlsSkipVerify"     toml:"tlsSkipVerify"`     // TLSの検証をスキップする
+	TLSServerName     string            `json:"tlsServerName"     yaml:"tlsServerName"     toml:"tlsServerName"`     // TLSのサーバー名(SNI)
+	TLSCAFile         string            `json:"tlsCaFile"         yaml:"tlsCaFile"         toml:"tlsCaFile"`         // TLSの検証に使うCAバンドルファイルパス
+	TLSClientCertFile string            `json:"tlsClientCertFile" yaml:"tlsClientCertFile" toml:"tlsClientCertFile"` // TLSのクライアント証明書ファイルパス
+	TLSClientKeyFile  string            `json:"tlsClientKeyFile"  yaml:"tlsClientKeyFile"  toml:"tlsClientKeyFile"`  // TLSのクライアントプライベートキーファイルパス
+	Timeout           uint32            `json:"timeout"           yaml:"timeout"           toml:"timeout"`           // タイムアウト
+	Retry             uint32            `json:"retry"             yaml:"retry"             toml:"retry"`             // リトライ回数
+	RetryWait         uint32            `json:"retryWait"         yaml:"retryWait"         toml:"retryWait"`         // 次のリトライまでの待ち時間
+}
+
+func (w *Webhook) validate() (bool) {
+	if w.URL == "" {
+		belog.Error("no url")
+		return false
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		belog.Error("invalid url (%v)", w.URL)
+		return false
+	}
+	if (w.TLSClientCertFile == "") != (w.TLSClientKeyFile == "") {
+		belog.Error("tlsClientCertFile and tlsClientKeyFile must be specified together")
+		return false
+	}
+	return true
+}
+
+// Chat is incoming webhook of slack or mattermost
+type Chat struct {
+	URL           string `json:"url"           yaml:"url"           toml:"url"`           // incoming webhookのurl
+	Channel       string `json:"channel"       yaml:"channel"       toml:"channel"`       // 投稿するチャンネル 空の場合はwebhookのデフォルト
+	Username      string `json:"username"      yaml:"username"      toml:"username"`      // 投稿するユーザ名 空の場合はwebhookのデフォルト
+	IconEmoji     string `json:"iconEmoji"     yaml:"iconEmoji"     toml:"iconEmoji"`     // アイコンの絵文字 (例 :warning:)
+	TLSSkipVerify bool   `json:"tlsSkipVerify" yaml:"tlsSkipVerify" toml:"tlsSkipVerify"` // TLSの検証をスキップする
+	Timeout       uint32 `json:"timeout"       yaml:"timeout"       toml:"timeout"`       // タイムアウト
+	Retry         uint32 `json:"retry"         yaml:"retry"         toml:"retry"`         // リトライ回数
+	RetryWait     uint32 `json:"retryWait"     yaml:"retryWait"     toml:"retryWait"`     // 次のリトライまでの待ち時間
+}
+
+func (c *Chat) validate() (bool) {
+	if c.URL == "" {
+		belog.Error("no url")
+		return false
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		belog.Error("invalid url (%v)", c.URL)
+		return false
+	}
+	return true
+}
+
+// Syslog is syslog of rfc5424
+type Syslog struct {
+	Network           string `json:"network"           yaml:"network"           toml:"network"`           // udp, tcp, tls, unix
+	Address           string `json:"address"           yaml:"address"           toml:"address"`           // 接続先ホストとポート unixの場合はソケットパス(空の場合は/dev/log)
+	Facility          string `json:"facility"          yaml:"facility"          toml:"facility"`          // ファシリティ 空の場合はdaemon
+	AppName           string `json:"appName"           yaml:"appName"           toml:"appName"`           // APP-NAME 空の場合はpdns-record-updater
+	StructuredDataID  string `json:"structuredDataId"  yaml:"structuredDataId"  toml:"structuredDataId"`  // structured dataのSD-ID 空の場合はpdns@32473
+	TLSSkipVerify     bool   `json:"tlsSkipVerify"     yaml:"tlsSkipVerify"     toml:"tlsSkipVerify"`     // TLSの検証をスキップする
+	TLSServerName     string `json:"tlsServerName"     yaml:"tlsServerName"     toml:"tlsServerName"`     // TLSのサーバー名(SNI)
+	TLSCAFile         string `json:"tlsCaFile"         yaml:"tlsCaFile"         toml:"tlsCaFile"`         // TLSの検証に使うCAバンドルファイルパス
+	TLSClientCertFile string `json:"tlsClientCertFile" yaml:"tlsClientCertFile" toml:"tlsClientCertFile"` // TLSのクライアント証明書ファイルパス
+	TLSClientKeyFile  string `json:"tlsClientKeyFile"  yaml:"tlsClientKeyFile"  toml:"tlsClientKeyFile"`  // TLSのクライアントプライベートキーファイルパス
+	Timeout           uint32 `json:"timeout"           yaml:"timeout"           toml:"timeout"`           // タイムアウト
+}
+
+func (s *Syslog) validate() (bool) {
+	switch strings.ToUpper(s.Network) {
+	case "UDP", "TCP", "TLS":
+		if s.Address == "" {
+			belog.Error("no address")
+			return false
+		}
+	case "UNIX":
+	default:
+		belog.Error("unexpected network (%v)", s.Network)
+		return false
+	}
+	if s.Facility != "" {
+		if _, ok := helper.SyslogFacilityMap[strings.ToLower(s.Facility)]; !ok {
+			belog.Error("unexpected facility (%v)", s.Facility)
+			return false
+		}
+	}
+	if s.StructuredDataID != "" && !strings.Contains(s.StructuredDataID, "@") {
+		belog.Error("structuredDataId must be name@<private enterprise number> (%v)", s.StructuredDataID)
+		return false
+	}
+	if (s.TLSClientCertFile == "") != (s.TLSClientKeyFile == "") {
+		belog.Error("tlsClientCertFile and tlsClientKeyFile must be specified together")
+		return false
+	}
+	return true
+}
+
+// Journald is journald
+type Journald struct {
+	SocketPath string `json:"socketPath" yaml:"socketPath" toml:"socketPath"` // journaldのソケットパス 空の場合は/run/systemd/journal/socket
+	Identifier string `json:"identifier" yaml:"identifier" toml:"identifier"` // SYSLOG_IDENTIFIER 空の場合はpdns-record-updater
+}
+
+func (j *Journald) validate() (bool) {
+	if j.SocketPath != "" && !strings.HasPrefix(j.SocketPath, "/") {
+		belog.Error("socketPath must be absolute path (%v)", j.SocketPath)
+		return false
+	}
+	if strings.ContainsAny(j.Identifier, " \t\r\n") {
+		belog.Error("identifier must not contain space or newline (%v)", j.Identifier)
+		return false
+	}
+	return true
+}
+
 // Notifier is Notifier
 type Notifier struct {
 	MailList []*Mail `json:"mailList" yaml:"mailList" toml:"mailList"` // メールリスト
+	WebhookList       []*Webhook  `json:"webhookList"       yaml:"webhookList"       toml:"webhookList"`       // webhookリスト
+	ChatList          []*Chat     `json:"chatList"          yaml:"chatList"          toml:"chatList"`          // slack, mattermostのincoming webhookリスト
+	SyslogList        []*Syslog   `json:"syslogList"        yaml:"syslogList"        toml:"syslogList"`        // syslogリスト
+	JournaldList      []*Journald `json:"journaldList"      yaml:"journaldList"      toml:"journaldList"`      // journaldリスト
+	AggregateWindow   uint32      `json:"aggregateWindow"   yaml:"aggregateWindow"   toml:"aggregateWindow"`   // この時間(秒)内の通知を通知先ごとに1つにまとめる 0の場合はまとめない まとめた通知の変数は全通知で同じ値ならその値、異なればmixed、%(count)は通知数
+	RateLimitCount    uint32      `json:"rateLimitCount"    yaml:"rateLimitCount"    toml:"rateLimitCount"`    // rateLimitInterval内に通知先ごとに送る最大数 超えた分は後でまとめて送る 0の場合は制限しない
+	RateLimitInterval uint32      `json:"rateLimitInterval" yaml:"rateLimitInterval" toml:"rateLimitInterval"` // rateLimitCountを数える時間(秒) 0の場合は60
 }
 
 func (n *Notifier) validate() (bool) {
@@ -1009,6 +2324,34 @@ func (n *Notifier) validate() (bool) {
 			}
 		}
 	}
+	if n.WebhookList != nil {
+		for _, webhook := range n.WebhookList {
+			if !webhook.validate() {
+				return false
+			}
+		}
+	}
+	if n.ChatList != nil {
+		for _, chat := range n.ChatList {
+			if !chat.validate() {
+				return false
+			}
+		}
+	}
+	if n.SyslogList != nil {
+		for _, syslog := range n.SyslogList {
+			if !syslog.validate() {
+				return false
+			}
+		}
+	}
+	if n.JournaldList != nil {
+		for _, journald := range n.JournaldList {
+			if !journald.validate() {
+				return false
+			}
+		}
+	}
 	return true
 }
 
@@ -1039,6 +2382,7 @@ type APIServer struct {
 	ListenList      []*Listen `json:"listenList"      yaml:"listenList"      toml:"listenList"`      // リッスンリスト
 	APIKey          string    `json:"apiKey"          yaml:"apiKey"          toml:"apiKey"`          // api key
 	LetsEncryptPath string    `json:"letsEncryptPath" yaml:"letsEncryptPath" toml:"letsEncryptPath"` // Staticリソースのパス
+	MetricsNoAuth   bool      `json:"metricsNoAuth"   yaml:"metricsNoAuth"   toml:"metricsNoAuth"`   // /metricsを認証なしで公開する (prometheusからscrapeする場合)
 }
 
 func (a *APIServer) validate() (bool) {
@@ -1117,6 +2461,9 @@ type Updater struct {
 	PdnsServer     string `json:"pdnsServer"     yaml:"pdnsServer"     toml:"pdnsServer"`     // power dns server url
         PdnsAPIKey     string `json:"pdnsApiKey"     yaml:"pdnsApiKey"     toml:"pdnsApiKey"`     // power dns api key
         SoaMinimumTTL  int32  `json:"soaMinimumTTL"  yaml:"soaMinimumTTL"  toml:"soaMinimumTTL"`  // soa minimum ttl
+	MetricsAddrPort string `json:"metricsAddrPort" yaml:"metricsAddrPort" toml:"metricsAddrPort"` // メトリクスをリッスンするアドレスとポート 空の場合はリッスンしない
+	ConsensusMode   string `json:"consensusMode"   yaml:"consensusMode"   toml:"consensusMode"`   // 複数のwatcherの結果から動的レコードの生存を決める方法 failover(デフォルト), majority, any, all, nOfM
+	ConsensusCount  uint32 `json:"consensusCount"  yaml:"consensusCount"  toml:"consensusCount"`  // nOfMの場合に生存とみなすのに必要なwatcherの数
 }
 
 func (u *Updater) validate() (bool) {
@@ -1128,6 +2475,17 @@ func (u *Updater) validate() (bool) {
                 belog.Error("invali soaMinimumTTL")
                 return false
 	}
+	switch strings.ToUpper(u.ConsensusMode) {
+	case "", "FAILOVER", "MAJORITY", "ANY", "ALL":
+	case "NOFM":
+		if u.ConsensusCount == 0 {
+			belog.Error("no consensusCount")
+			return false
+		}
+	default:
+		belog.Error("unexpected consensusMode (%v)", u.ConsensusMode)
+		return false
+	}
 	return true
 }
 
@@ -1175,6 +2533,10 @@ func (c *Context) validate(mode string) (bool) {
 		if c.APIClient  == nil || c.Initializer == nil || c.Updater == nil {
 			return false
 		}
+		if strings.ToUpper(c.Updater.ConsensusMode) == "NOFM" && int(c.Updater.ConsensusCount) > len(c.APIClient.APIServerURLList) {
+			belog.Error("consensusCount (%v) is greater than length of apiServerUrlList", c.Updater.ConsensusCount)
+			return false
+		}
 	case "MANAGER":
 		if c.APIClient  == nil || c.Manager == nil {
 			return false
@@ -1306,11 +2668,30 @@ func (c *Contexter) LoadConfig() (error){
 	return nil
 }
 
+// configView is context for saving, targets are replaced with ones before resolving template
+func (c *Context) configView() (*Context) {
+	if c.Watcher == nil || c.Watcher.TargetMap == nil {
+		return c
+	}
+	newWatcher := *c.Watcher
+	newWatcher.TargetMap = make(map[string]*Target, len(c.Watcher.TargetMap))
+	for targetName, target := range c.Watcher.TargetMap {
+		if target.raw != nil {
+			newWatcher.TargetMap[targetName] = target.raw
+		} else {
+			newWatcher.TargetMap[targetName] = target
+		}
+	}
+	newContext := *c
+	newContext.Watcher = &newWatcher
+	return &newContext
+}
+
 // SaveConfig is save config
 func (c *Contexter) SaveConfig() (error) {
 	mutableMutex.Lock()
         defer mutableMutex.Unlock()
-	return c.configurator.Save(c.Context)
+	return c.configurator.Save(c.Context.configView())
 }
 
 // GetContext is get context
@@ -1321,19 +2702,19 @@ func (c *Contexter) GetContext(format string) ([]byte, error) {
         case "toml":
                 var buffer bytes.Buffer
                 encoder := toml.NewEncoder(&buffer)
-                err := encoder.Encode(c.Context)
+                err := encoder.Encode(c.Context.configView())
                 if err != nil {
                         return nil, errors.Wrap(err, "can not encode with toml")
                 }
                 return buffer.Bytes(), nil
         case "yaml":
-                y, err := yaml.Marshal(c.Context)
+                y, err := yaml.Marshal(c.Context.configView())
                 if err != nil {
                         return nil, errors.Wrap(err, "can not encode with yaml")
                 }
 		return y, nil
         case "json":
-                j, err := json.Marshal(c.Context)
+                j, err := json.Marshal(c.Context.configView())
                 if err != nil {
                         return nil, errors.Wrap(err, "can not encode with json")
                 }
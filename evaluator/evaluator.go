@@ -0,0 +1,384 @@
+package evaluator
+
+import (
+	"github.com/pkg/errors"
+	"strconv"
+	"fmt"
+)
+
+// Env is environment that provides target state to rule
+type Env interface {
+	GetAlive(targetName string) (bool, error)
+	GetAttribute(targetName string, attribute string) (float64, error)
+}
+
+// attributeMap is attribute name that can be referred as target.attribute
+var attributeMap = map[string]valueType {
+	"alive":   vtBool,
+	"latency": vtNumber,
+	"loss":    vtNumber,
+	"minRtt":  vtNumber,
+	"avgRtt":  vtNumber,
+	"maxRtt":  vtNumber,
+}
+
+// funcMap is minimum argument count of function
+var funcMap = map[string]int {
+	"atLeast": 2,
+	"any":     1,
+	"all":     1,
+	"count":   1,
+	"percent": 1,
+}
+
+// Rule is compiled eval rule
+type Rule struct {
+	source         string
+	root           node
+	targetNameList []string
+}
+
+type parser struct {
+	tokenList      []*token
+	pos            int
+	targetNameMap  map[string]bool
+	allowNameList  []string
+	referredMap    map[string]bool
+	targetNameList []string
+}
+
+func (p *parser) peek() (*token) {
+	return p.tokenList[p.pos]
+}
+
+func (p *parser) next() (*token) {
+	t := p.tokenList[p.pos]
+	if t.kind != tkEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind, value string) (*token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return nil, errors.Errorf("expected %v but found (%v) at %v", value, t.value, t.pos)
+	}
+	return t, nil
+}
+
+func (p *parser) isOperator(valueList ...string) (bool) {
+	t := p.peek()
+	if t.kind != tkOperator {
+		return false
+	}
+	for _, value := range valueList {
+		if t.value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func checkType(t *token, n node, expected valueType) (error) {
+	if n.valueType() != expected {
+		return errors.Errorf("operand of (%v) at %v must be %v", t.value, t.pos, expected)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		t := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkType(t, left, vtBool); err != nil {
+			return nil, err
+		}
+		if err := checkType(t, right, vtBool); err != nil {
+			return nil, err
+		}
+		left = &binaryNode{ operator: t.value, left: left, right: right }
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		t := p.next()
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkType(t, left, vtBool); err != nil {
+			return nil, err
+		}
+		if err := checkType(t, right, vtBool); err != nil {
+			return nil, err
+		}
+		left = &binaryNode{ operator: t.value, left: left, right: right }
+	}
+	return left, nil
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOperator("==", "!=", "<", "<=", ">", ">=") {
+		return left, nil
+	}
+	t := p.next()
+	right, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	if t.value == "==" || t.value == "!=" {
+		if left.valueType() != right.valueType() {
+			return nil, errors.Errorf("mismatched operand type of (%v) at %v", t.value, t.pos)
+		}
+	} else {
+		if err := checkType(t, left, vtNumber); err != nil {
+			return nil, err
+		}
+		if err := checkType(t, right, vtNumber); err != nil {
+			return nil, err
+		}
+	}
+	return &binaryNode{ operator: t.value, left: left, right: right }, nil
+}
+
+func (p *parser) parseAdd() (node, error) {
+	left, err := p.parseMul()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("+", "-") {
+		t := p.next()
+		right, err := p.parseMul()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkType(t, left, vtNumber); err != nil {
+			return nil, err
+		}
+		if err := checkType(t, right, vtNumber); err != nil {
+			return nil, err
+		}
+		left = &binaryNode{ operator: t.value, left: left, right: right }
+	}
+	return left, nil
+}
+
+func (p *parser) parseMul() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("*", "/") {
+		t := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkType(t, left, vtNumber); err != nil {
+			return nil, err
+		}
+		if err := checkType(t, right, vtNumber); err != nil {
+			return nil, err
+		}
+		left = &binaryNode{ operator: t.value, left: left, right: right }
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("!", "-") {
+		t := p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if t.value == "!" {
+			if err := checkType(t, operand, vtBool); err != nil {
+				return nil, err
+			}
+		} else {
+			if err := checkType(t, operand, vtNumber); err != nil {
+				return nil, err
+			}
+		}
+		return &unaryNode{ operator: t.value, operand: operand }, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tkNumber:
+		n, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("can not parse number (%v) at %v", t.value, t.pos))
+		}
+		if p.peek().kind == tkPercent {
+			p.next()
+			return &numberLiteral{ n: n, percent: true }, nil
+		}
+		return &numberLiteral{ n: n }, nil
+	case tkLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tkRParen, ")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case tkRef:
+		return p.parseTarget(t)
+	case tkIdent:
+		if p.peek().kind == tkLParen {
+			return p.parseFunc(t)
+		}
+		switch t.value {
+		case "true":
+			return &boolLiteral{ b: true }, nil
+		case "false":
+			return &boolLiteral{ b: false }, nil
+		}
+		return p.parseTarget(t)
+	default:
+		return nil, errors.Errorf("unexpected (%v) at %v", t.value, t.pos)
+	}
+}
+
+func (p *parser) parseTarget(t *token) (node, error) {
+	if !p.targetNameMap[t.value] {
+		return nil, errors.Errorf("unknown target (%v) at %v, not in targetNameList (%v)", t.value, t.pos, p.allowNameList)
+	}
+	if !p.referredMap[t.value] {
+		p.referredMap[t.value] = true
+		p.targetNameList = append(p.targetNameList, t.value)
+	}
+	if p.peek().kind != tkDot {
+		return &targetAlive{ targetName: t.value }, nil
+	}
+	p.next()
+	a, err := p.expect(tkIdent, "attribute")
+	if err != nil {
+		return nil, err
+	}
+	vt, ok := attributeMap[a.value]
+	if !ok {
+		return nil, errors.Errorf("unknown attribute (%v) at %v", a.value, a.pos)
+	}
+	if vt == vtBool {
+		return &targetAlive{ targetName: t.value }, nil
+	}
+	return &targetAttribute{ targetName: t.value, attribute: a.value }, nil
+}
+
+func (p *parser) parseFunc(t *token) (node, error) {
+	minArgs, ok := funcMap[t.value]
+	if !ok {
+		return nil, errors.Errorf("unknown function (%v) at %v", t.value, t.pos)
+	}
+	p.next()
+	argList := make([]node, 0, 4)
+	if p.peek().kind != tkRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			argList = append(argList, arg)
+			if p.peek().kind != tkComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if _, err := p.expect(tkRParen, ")"); err != nil {
+		return nil, err
+	}
+	if len(argList) < minArgs {
+		return nil, errors.Errorf("too few arguments of (%v) at %v", t.value, t.pos)
+	}
+	boolArgList := argList
+	if t.value == "atLeast" {
+		if _, ok := argList[0].(*numberLiteral); !ok {
+			return nil, errors.Errorf("first argument of (%v) at %v must be number or percentage", t.value, t.pos)
+		}
+		boolArgList = argList[1:]
+	}
+	for _, arg := range boolArgList {
+		if arg.valueType() != vtBool {
+			return nil, errors.Errorf("arguments of (%v) at %v must be bool", t.value, t.pos)
+		}
+	}
+	return &funcNode{ name: t.value, argList: argList }, nil
+}
+
+// Compile is compile rule, targets referred by rule must be in targetNameList
+func Compile(source string, targetNameList []string) (*Rule, error) {
+	tokenList, err := tokenize(source)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("can not tokenize rule (%v)", source))
+	}
+	p := &parser{
+		tokenList:      tokenList,
+		targetNameMap:  make(map[string]bool),
+		allowNameList:  targetNameList,
+		referredMap:    make(map[string]bool),
+		targetNameList: make([]string, 0, len(targetNameList)),
+	}
+	for _, targetName := range targetNameList {
+		p.targetNameMap[targetName] = true
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("can not parse rule (%v)", source))
+	}
+	if t := p.peek(); t.kind != tkEOF {
+		return nil, errors.Errorf("can not parse rule (%v): unexpected (%v) at %v", source, t.value, t.pos)
+	}
+	if root.valueType() != vtBool {
+		return nil, errors.Errorf("can not parse rule (%v): result must be bool", source)
+	}
+	return &Rule{
+		source:         source,
+		root:           root,
+		targetNameList: p.targetNameList,
+	}, nil
+}
+
+// Eval is evaluate rule
+func (r *Rule) Eval(env Env) (bool, error) {
+	v, err := r.root.eval(env)
+	if err != nil {
+		return false, errors.Wrap(err, fmt.Sprintf("can not evaluate rule (%v)", r.source))
+	}
+	return v.b, nil
+}
+
+// GetTargetNameList is get target name list referred by rule
+func (r *Rule) GetTargetNameList() ([]string) {
+	return r.targetNameList
+}
+
+// String is string
+func (r *Rule) String() (string) {
+	return r.source
+}
@@ -0,0 +1,113 @@
+package evaluator
+
+import (
+	"github.com/pkg/errors"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tkEOF tokenKind = iota
+	tkIdent
+	tkNumber
+	tkRef
+	tkOperator
+	tkLParen
+	tkRParen
+	tkComma
+	tkDot
+	tkPercent
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func isIdentStart(c byte) (bool) {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) (bool) {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) (bool) {
+	return c >= '0' && c <= '9'
+}
+
+var operatorList = []string{ "&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/" }
+
+func tokenize(source string) ([]*token, error) {
+	tokenList := make([]*token, 0, len(source) / 2)
+	i := 0
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '%' && i + 1 < len(source) && source[i + 1] == '(':
+			// %(target name)
+			end := strings.IndexByte(source[i + 2:], ')')
+			if end < 0 {
+				return nil, errors.Errorf("unterminated target reference at %v", i)
+			}
+			name := strings.TrimSpace(source[i + 2:i + 2 + end])
+			if name == "" {
+				return nil, errors.Errorf("empty target reference at %v", i)
+			}
+			tokenList = append(tokenList, &token{ kind: tkRef, value: name, pos: i })
+			i += end + 3
+		case c == '%':
+			tokenList = append(tokenList, &token{ kind: tkPercent, value: "%", pos: i })
+			i++
+		case c == '(':
+			tokenList = append(tokenList, &token{ kind: tkLParen, value: "(", pos: i })
+			i++
+		case c == ')':
+			tokenList = append(tokenList, &token{ kind: tkRParen, value: ")", pos: i })
+			i++
+		case c == ',':
+			tokenList = append(tokenList, &token{ kind: tkComma, value: ",", pos: i })
+			i++
+		case c == '.':
+			tokenList = append(tokenList, &token{ kind: tkDot, value: ".", pos: i })
+			i++
+		case isDigit(c):
+			start := i
+			for i < len(source) && isDigit(source[i]) {
+				i++
+			}
+			if i + 1 < len(source) && source[i] == '.' && isDigit(source[i + 1]) {
+				i++
+				for i < len(source) && isDigit(source[i]) {
+					i++
+				}
+			}
+			tokenList = append(tokenList, &token{ kind: tkNumber, value: source[start:i], pos: start })
+		case isIdentStart(c):
+			start := i
+			for i < len(source) && isIdentPart(source[i]) {
+				i++
+			}
+			tokenList = append(tokenList, &token{ kind: tkIdent, value: source[start:i], pos: start })
+		default:
+			matched := false
+			for _, operator := range operatorList {
+				if strings.HasPrefix(source[i:], operator) {
+					tokenList = append(tokenList, &token{ kind: tkOperator, value: operator, pos: i })
+					i += len(operator)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, errors.Errorf("unexpected character (%v) at %v", string(c), i)
+			}
+		}
+	}
+	tokenList = append(tokenList, &token{ kind: tkEOF, value: "end of rule", pos: len(source) })
+	return tokenList, nil
+}
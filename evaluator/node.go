@@ -0,0 +1,257 @@
+package evaluator
+
+import (
+	"github.com/pkg/errors"
+	"fmt"
+)
+
+type valueType int
+
+const (
+	vtBool valueType = iota
+	vtNumber
+)
+
+func (v valueType) String() (string) {
+	switch v {
+	case vtBool:
+		return "bool"
+	case vtNumber:
+		return "number"
+	default:
+		return "unknown"
+	}
+}
+
+type value struct {
+	b bool
+	n float64
+}
+
+type node interface {
+	valueType() (valueType)
+	eval(env Env) (value, error)
+}
+
+type boolLiteral struct {
+	b bool
+}
+
+func (b *boolLiteral) valueType() (valueType) {
+	return vtBool
+}
+
+func (b *boolLiteral) eval(env Env) (value, error) {
+	return value{ b: b.b }, nil
+}
+
+type numberLiteral struct {
+	n       float64
+	percent bool
+}
+
+func (n *numberLiteral) valueType() (valueType) {
+	return vtNumber
+}
+
+func (n *numberLiteral) eval(env Env) (value, error) {
+	return value{ n: n.n }, nil
+}
+
+type targetAlive struct {
+	targetName string
+}
+
+func (t *targetAlive) valueType() (valueType) {
+	return vtBool
+}
+
+func (t *targetAlive) eval(env Env) (value, error) {
+	alive, err := env.GetAlive(t.targetName)
+	if err != nil {
+		return value{}, err
+	}
+	return value{ b: alive }, nil
+}
+
+type targetAttribute struct {
+	targetName string
+	attribute  string
+}
+
+func (t *targetAttribute) valueType() (valueType) {
+	return vtNumber
+}
+
+func (t *targetAttribute) eval(env Env) (value, error) {
+	n, err := env.GetAttribute(t.targetName, t.attribute)
+	if err != nil {
+		return value{}, err
+	}
+	return value{ n: n }, nil
+}
+
+type unaryNode struct {
+	operator string
+	operand  node
+}
+
+func (u *unaryNode) valueType() (valueType) {
+	return u.operand.valueType()
+}
+
+func (u *unaryNode) eval(env Env) (value, error) {
+	v, err := u.operand.eval(env)
+	if err != nil {
+		return value{}, err
+	}
+	switch u.operator {
+	case "!":
+		return value{ b: !v.b }, nil
+	case "-":
+		return value{ n: -v.n }, nil
+	default:
+		panic("not reached")
+	}
+}
+
+type binaryNode struct {
+	operator string
+	left     node
+	right    node
+}
+
+func (b *binaryNode) valueType() (valueType) {
+	switch b.operator {
+	case "+", "-", "*", "/":
+		return vtNumber
+	default:
+		return vtBool
+	}
+}
+
+func (b *binaryNode) eval(env Env) (value, error) {
+	l, err := b.left.eval(env)
+	if err != nil {
+		return value{}, err
+	}
+	// short circuit
+	switch b.operator {
+	case "&&":
+		if !l.b {
+			return value{ b: false }, nil
+		}
+	case "||":
+		if l.b {
+			return value{ b: true }, nil
+		}
+	}
+	r, err := b.right.eval(env)
+	if err != nil {
+		return value{}, err
+	}
+	switch b.operator {
+	case "&&", "||":
+		return value{ b: r.b }, nil
+	case "==":
+		if b.left.valueType() == vtBool {
+			return value{ b: l.b == r.b }, nil
+		}
+		return value{ b: l.n == r.n }, nil
+	case "!=":
+		if b.left.valueType() == vtBool {
+			return value{ b: l.b != r.b }, nil
+		}
+		return value{ b: l.n != r.n }, nil
+	case "<":
+		return value{ b: l.n < r.n }, nil
+	case "<=":
+		return value{ b: l.n <= r.n }, nil
+	case ">":
+		return value{ b: l.n > r.n }, nil
+	case ">=":
+		return value{ b: l.n >= r.n }, nil
+	case "+":
+		return value{ n: l.n + r.n }, nil
+	case "-":
+		return value{ n: l.n - r.n }, nil
+	case "*":
+		return value{ n: l.n * r.n }, nil
+	case "/":
+		if r.n == 0 {
+			return value{}, errors.Errorf("division by zero")
+		}
+		return value{ n: l.n / r.n }, nil
+	default:
+		panic("not reached")
+	}
+}
+
+type funcNode struct {
+	name    string
+	argList []node
+}
+
+func (f *funcNode) valueType() (valueType) {
+	switch f.name {
+	case "count", "percent":
+		return vtNumber
+	default:
+		return vtBool
+	}
+}
+
+func (f *funcNode) countTrue(argList []node, env Env) (float64, error) {
+	var count float64
+	for _, arg := range argList {
+		v, err := arg.eval(env)
+		if err != nil {
+			return 0, err
+		}
+		if v.b {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *funcNode) eval(env Env) (value, error) {
+	switch f.name {
+	case "atLeast":
+		threshold := f.argList[0].(*numberLiteral)
+		count, err := f.countTrue(f.argList[1:], env)
+		if err != nil {
+			return value{}, err
+		}
+		if threshold.percent {
+			return value{ b: count * 100 >= threshold.n * float64(len(f.argList) - 1) }, nil
+		}
+		return value{ b: count >= threshold.n }, nil
+	case "any":
+		count, err := f.countTrue(f.argList, env)
+		if err != nil {
+			return value{}, err
+		}
+		return value{ b: count > 0 }, nil
+	case "all":
+		count, err := f.countTrue(f.argList, env)
+		if err != nil {
+			return value{}, err
+		}
+		return value{ b: count == float64(len(f.argList)) }, nil
+	case "count":
+		count, err := f.countTrue(f.argList, env)
+		if err != nil {
+			return value{}, err
+		}
+		return value{ n: count }, nil
+	case "percent":
+		count, err := f.countTrue(f.argList, env)
+		if err != nil {
+			return value{}, err
+		}
+		return value{ n: count * 100 / float64(len(f.argList)) }, nil
+	default:
+		panic(fmt.Sprintf("not reached (%v)", f.name))
+	}
+}
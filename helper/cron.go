@@ -0,0 +1,172 @@
+package helper
+
+import (
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+	"time"
+	"fmt"
+)
+
+// CronSchedule is parsed cron expression (minute hour day-of-month month day-of-week)
+type CronSchedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+	domStar    bool
+	dowStar    bool
+}
+
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFieldList = []cronField{
+	{ name: "minute",       min: 0, max: 59 },
+	{ name: "hour",         min: 0, max: 23 },
+	{ name: "day of month", min: 1, max: 31 },
+	{ name: "month",        min: 1, max: 12 },
+	{ name: "day of week",  min: 0, max: 7 },
+}
+
+func parseCronField(field string, cf cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i + 1:])
+			if err != nil || s <= 0 {
+				return 0, errors.Errorf("invalid step (%v) of %v", part, cf.name)
+			}
+			step = s
+			part = part[:i]
+		}
+		start, end := cf.min, cf.max
+		if part != "*" {
+			rangeList := strings.SplitN(part, "-", 2)
+			s, err := strconv.Atoi(rangeList[0])
+			if err != nil {
+				return 0, errors.Errorf("invalid value (%v) of %v", part, cf.name)
+			}
+			start, end = s, s
+			if len(rangeList) == 2 {
+				e, err := strconv.Atoi(rangeList[1])
+				if err != nil {
+					return 0, errors.Errorf("invalid value (%v) of %v", part, cf.name)
+				}
+				end = e
+			} else if step != 1 {
+				// "n/step" means from n to max
+				end = cf.max
+			}
+		}
+		if start < cf.min || end > cf.max || start > end {
+			return 0, errors.Errorf("out of range (%v) of %v", part, cf.name)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// ParseCron is parse cron expression, e.g. "0 3 * * 0" (every sunday 03:00)
+func ParseCron(spec string) (*CronSchedule, error) {
+	fieldList := strings.Fields(spec)
+	if len(fieldList) != len(cronFieldList) {
+		return nil, errors.Errorf("cron expression (%v) must have %v fields", spec, len(cronFieldList))
+	}
+	bitsList := make([]uint64, len(cronFieldList))
+	for i, field := range fieldList {
+		bits, err := parseCronField(field, cronFieldList[i])
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("can not parse cron expression (%v)", spec))
+		}
+		bitsList[i] = bits
+	}
+	// both 0 and 7 are sunday
+	if bitsList[4] & (1 << 7) != 0 {
+		bitsList[4] |= 1
+	}
+	return &CronSchedule{
+		minute:     bitsList[0],
+		hour:       bitsList[1],
+		dayOfMonth: bitsList[2],
+		month:      bitsList[3],
+		dayOfWeek:  bitsList[4],
+		domStar:    strings.HasPrefix(fieldList[2], "*"),
+		dowStar:    strings.HasPrefix(fieldList[4], "*"),
+	}, nil
+}
+
+// Match is whether the minute of t matches schedule
+func (c *CronSchedule) Match(t time.Time) (bool) {
+	if c.minute & (1 << uint(t.Minute())) == 0 ||
+	    c.hour & (1 << uint(t.Hour())) == 0 ||
+	    c.month & (1 << uint(t.Month())) == 0 {
+		return false
+	}
+	return c.matchDay(t)
+}
+
+func (c *CronSchedule) matchDay(t time.Time) (bool) {
+	domMatch := c.dayOfMonth & (1 << uint(t.Day())) != 0
+	dowMatch := c.dayOfWeek & (1 << uint(t.Weekday())) != 0
+	// same as cron, when both day of month and day of week are restricted, either one matches
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// prevBit is get largest set bit of bits below n, return -1 if not found
+func prevBit(bits uint64, n int) (int) {
+	for i := n - 1; i >= 0; i-- {
+		if bits & (1 << uint(i)) != 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// LastStart is get latest start time in (now - within, now], return zero time if not found
+func (c *CronSchedule) LastStart(now time.Time, within time.Duration) (time.Time) {
+	t := now.Truncate(time.Minute)
+	limit := now.Add(-within)
+	// go back field by field, skipping whole months, days and hours that do not match
+	for t.After(limit) {
+		if c.Match(t) {
+			return t
+		}
+		year, month, day := t.Date()
+		location := t.Location()
+		var prev time.Time
+		if c.month & (1 << uint(month)) == 0 {
+			prev = time.Date(year, month, 1, 0, 0, 0, 0, location).Add(-time.Minute)
+		} else if !c.matchDay(t) {
+			prev = time.Date(year, month, day, 0, 0, 0, 0, location).Add(-time.Minute)
+		} else if c.hour & (1 << uint(t.Hour())) == 0 {
+			if hour := prevBit(c.hour, t.Hour()); hour >= 0 {
+				prev = time.Date(year, month, day, hour, 59, 0, 0, location)
+			} else {
+				prev = time.Date(year, month, day, 0, 0, 0, 0, location).Add(-time.Minute)
+			}
+		} else {
+			if minute := prevBit(c.minute, t.Minute()); minute >= 0 {
+				prev = time.Date(year, month, day, t.Hour(), minute, 0, 0, location)
+			} else {
+				prev = time.Date(year, month, day, t.Hour(), 0, 0, 0, location).Add(-time.Minute)
+			}
+		}
+		if !prev.Before(t) {
+			// wall clock was normalized forward around daylight saving time transition
+			prev = t.Add(-time.Minute)
+		}
+		t = prev
+	}
+	return time.Time{}
+}
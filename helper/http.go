@@ -1,12 +1,29 @@
 package helper
 
 import(
+        "github.com/pkg/errors"
         "net"
         "net/http"
         "crypto/tls"
+        "crypto/x509"
+        "io/ioutil"
+        "strings"
         "time"
+        "fmt"
 )
 
+// HTTPClientOption is option of http client
+type HTTPClientOption struct {
+	TLSSkipVerify     bool
+	TLSServerName     string
+	TLSCAFile         string
+	TLSClientCertFile string
+	TLSClientKeyFile  string
+	RedirectPolicy    string // follow, none, sameHost
+	Timeout           uint32
+	TLSConfig         *tls.Config // parsed tls config, when not nil it is used instead of tls options
+}
+
 func newHTTPTransport() (transport *http.Transport) {
         return &http.Transport{
                 Proxy: http.ProxyFromEnvironment,
@@ -19,14 +36,85 @@ func newHTTPTransport() (transport *http.Transport) {
         }
 }
 
+func newTLSConfig(host string, option *HTTPClientOption) (*tls.Config, error) {
+	serverName := option.TLSServerName
+	if serverName == "" {
+		serverName = host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			serverName = h
+		}
+	}
+	tlsConfig := &tls.Config{ServerName: serverName, InsecureSkipVerify: option.TLSSkipVerify}
+	if option.TLSCAFile != "" {
+		pem, err := ioutil.ReadFile(option.TLSCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("can not read ca file (%v)", option.TLSCAFile))
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("can not append ca certificates (%v)", option.TLSCAFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	if option.TLSClientCertFile != "" || option.TLSClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(option.TLSClientCertFile, option.TLSClientKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("can not load client certificate (%v) (%v)", option.TLSClientCertFile, option.TLSClientKeyFile))
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func newCheckRedirect(redirectPolicy string) (func(req *http.Request, via []*http.Request) error, error) {
+	switch strings.ToUpper(redirectPolicy) {
+	case "", "FOLLOW":
+		return nil, nil
+	case "NONE":
+		return func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}, nil
+	case "SAMEHOST":
+		return func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.Errorf("stopped after 10 redirects")
+			}
+			if req.URL.Host != via[0].URL.Host {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		}, nil
+	default:
+		return nil, errors.Errorf("unsupported redirect policy (%v)", redirectPolicy)
+	}
+}
+
 // NewHTTPClient is new http client
-func NewHTTPClient(scheme string, host string, tlsSkipVerify bool, timeout uint32) (*http.Client) {
+func NewHTTPClient(scheme string, host string, option *HTTPClientOption) (*http.Client, error) {
         transport := newHTTPTransport()
         if scheme == "https" {
-                transport.TLSClientConfig = &tls.Config{ServerName: host, InsecureSkipVerify: tlsSkipVerify}
+		tlsConfig := option.TLSConfig
+		if tlsConfig == nil {
+			var err error
+			tlsConfig, err = newTLSConfig(host, option)
+			if err != nil {
+				return nil, err
+			}
+		}
+                transport.TLSClientConfig = tlsConfig
         }
+	checkRedirect, err := newCheckRedirect(option.RedirectPolicy)
+	if err != nil {
+		return nil, err
+	}
         return &http.Client{
                 Transport: transport,
-                Timeout: time.Duration(timeout) * time.Second,
-        }
+                CheckRedirect: checkRedirect,
+                Timeout: time.Duration(option.Timeout) * time.Second,
+        }, nil
+}
+
+// NewTLSConfig is new tls config with tls options of http client option
+func NewTLSConfig(host string, option *HTTPClientOption) (*tls.Config, error) {
+	return newTLSConfig(host, option)
 }
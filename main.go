@@ -51,7 +51,10 @@ func runUpdater(contexter *contexter.Contexter) (error) {
 		return err
 	}
 	updater := updater.New(contexter.Context, client)
-	updater.Start()
+	err = updater.Start()
+	if err != nil {
+		return err
+	}
 	signalWait()
 	updater.Stop()
 	return nil
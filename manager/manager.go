@@ -6,6 +6,7 @@ import (
         "github.com/gin-gonic/gin"
 	"github.com/gin-gonic/contrib/sessions"
         "github.com/potix/pdns-record-updater/contexter"
+        "github.com/potix/pdns-record-updater/metrics"
         "github.com/potix/pdns-record-updater/api/client"
         "net/http"
         "path/filepath"
@@ -68,6 +69,7 @@ func (m *Manager) Start() (err error) {
                 errors.Errorf("not found linten port")
         }
 	engine := gin.Default()
+	engine.Use(metrics.CountAPIRequest)
 	store := sessions.NewCookieStore([]byte("secret"))
 	engine.Use(sessions.Sessions("pdns-record-updater-session", store))
 
@@ -76,6 +78,7 @@ func (m *Manager) Start() (err error) {
 	m.addEngineGetHandler(engine, "/index.html", m.index)                  // index
 	m.addEngineGetHandler(engine, "/bower_components/*wildcard", m.asset)  // asset
 	m.addEnginePostHandler(engine, "/login", m.login)                      // login
+	m.addEngineGetHandler(engine, "/metrics", metrics.GinHandler())        // metrics
 	m.addEngineGetHandler(engine, "/logout", m.logout)                     // logout
 	newGroup := engine.Group("/mngmnt", m.checkSession)
 	m.addGroupGetHandler(newGroup, "/", m.mngmntIndex)                     // management index
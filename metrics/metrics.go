@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "pdns_record_updater"
+
+var (
+	probeDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "watcher",
+			Name:      "probe_duration_seconds",
+			Help:      "Duration of probe per target and protocol.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"target", "protocol"},
+	)
+	probeTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "watcher",
+			Name:      "probe_total",
+			Help:      "Number of probes per target, protocol and result.",
+		},
+		[]string{"target", "protocol", "result"},
+	)
+	dynamicRecordAlive = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "watcher",
+			Name:      "dynamic_record_alive",
+			Help:      "Alive of dynamic record (1 is alive, 0 is down).",
+		},
+		[]string{"domain", "group", "name", "type", "content"},
+	)
+	notificationTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "notifier",
+			Name:      "notification_total",
+			Help:      "Number of notifications per notifier type and result.",
+		},
+		[]string{"type", "result"},
+	)
+	pdnsRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "updater",
+			Name:      "pdns_request_duration_seconds",
+			Help:      "Latency of request to power dns api per method.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"method"},
+	)
+	pdnsRequestTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "updater",
+			Name:      "pdns_request_total",
+			Help:      "Number of requests to power dns api per method and status code (0 is connection error).",
+		},
+		[]string{"method", "code"},
+	)
+	apiRequestTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "api",
+			Name:      "request_total",
+			Help:      "Number of api requests per method, route and status code.",
+		},
+		[]string{"method", "route", "code"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		probeDuration,
+		probeTotal,
+		dynamicRecordAlive,
+		notificationTotal,
+		pdnsRequestDuration,
+		pdnsRequestTotal,
+		apiRequestTotal,
+	)
+}
+
+func resultLabel(success bool) (string) {
+	if success {
+		return "success"
+	}
+	return "failure"
+}
+
+// ObserveProbe is observe probe result of target
+func ObserveProbe(targetName string, protocol string, duration time.Duration, success bool) {
+	probeDuration.WithLabelValues(targetName, protocol).Observe(duration.Seconds())
+	probeTotal.WithLabelValues(targetName, protocol, resultLabel(success)).Inc()
+}
+
+// DeleteProbe is delete probe metrics of target that is deleted or whose protocol is changed
+func DeleteProbe(targetName string, protocol string) {
+	probeDuration.DeleteLabelValues(targetName, protocol)
+	probeTotal.DeleteLabelValues(targetName, protocol, resultLabel(true))
+	probeTotal.DeleteLabelValues(targetName, protocol, resultLabel(false))
+}
+
+// SetDynamicRecordAlive is set alive of dynamic record
+func SetDynamicRecordAlive(domain string, groupName string, name string, recordType string, content string, alive bool) {
+	var value float64
+	if alive {
+		value = 1
+	}
+	dynamicRecordAlive.WithLabelValues(domain, groupName, name, recordType, content).Set(value)
+}
+
+// DeleteDynamicRecordAlive is delete alive of dynamic record that is deleted
+func DeleteDynamicRecordAlive(domain string, groupName string, name string, recordType string, content string) {
+	dynamicRecordAlive.DeleteLabelValues(domain, groupName, name, recordType, content)
+}
+
+// CountNotification is count notification result
+func CountNotification(notifierType string, err error) {
+	notificationTotal.WithLabelValues(notifierType, resultLabel(err == nil)).Inc()
+}
+
+// ObservePdnsRequest is observe request to power dns api, statusCode is 0 when no response
+func ObservePdnsRequest(method string, statusCode int, duration time.Duration) {
+	pdnsRequestDuration.WithLabelValues(method).Observe(duration.Seconds())
+	pdnsRequestTotal.WithLabelValues(method, strconv.Itoa(statusCode)).Inc()
+}
+
+// CountAPIRequest is gin middleware that counts api request per route
+func CountAPIRequest(context *gin.Context) {
+	context.Next()
+	route := context.FullPath()
+	if route == "" {
+		route = "unknown"
+	}
+	apiRequestTotal.WithLabelValues(context.Request.Method, route, strconv.Itoa(context.Writer.Status())).Inc()
+}
+
+// Handler is http handler of metrics in prometheus exposition format
+func Handler() (http.Handler) {
+	return promhttp.Handler()
+}
+
+// GinHandler is gin handler of metrics in prometheus exposition format
+func GinHandler() (gin.HandlerFunc) {
+	return gin.WrapH(promhttp.Handler())
+}
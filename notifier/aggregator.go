@@ -0,0 +1,305 @@
+package notifier
+
+import (
+	"github.com/potix/belog"
+	"github.com/potix/pdns-record-updater/contexter"
+	"strings"
+	"sync"
+	"time"
+	"fmt"
+)
+
+const (
+	defaultRateLimitInterval time.Duration = 60 * time.Second
+	aggregatorTickInterval   time.Duration = time.Second
+	// bodies of more events than this are omitted from digest
+	maxDigestBodyCount int = 20
+	// value of digest variable that differs between notifications
+	digestMixedValue string = "mixed"
+)
+
+type clock interface {
+	Now() (time.Time)
+}
+
+type realClock struct{}
+
+func (r realClock) Now() (time.Time) {
+	return time.Now()
+}
+
+type aggregateEvent struct {
+	key             string
+	varList         []string
+	subject         string
+	body            string
+	renderedSubject string
+	count           int
+}
+
+// eventKeyVariableList is variables that identify notification, other variables such as time and detail are ignored
+var eventKeyVariableList = []string{
+	"%(domain)",
+	"%(groupName)",
+	"%(name)",
+	"%(type)",
+	"%(content)",
+	"%(newAlive)",
+	"%(flapping)",
+	"%(targetName)",
+	"%(alive)",
+}
+
+// eventKey is key to deduplicate identical notifications
+func eventKey(varList []string) (string) {
+	varMap := newVarMap(varList)
+	keyList := make([]string, 0, len(eventKeyVariableList))
+	for _, variable := range eventKeyVariableList {
+		if value, ok := varMap[variable]; ok {
+			keyList = append(keyList, variable + "=" + value)
+		}
+	}
+	return strings.Join(keyList, "\n")
+}
+
+type aggregateChannel struct {
+	sink         *sink
+	eventList    []*aggregateEvent
+	eventMap     map[string]*aggregateEvent
+	firstTime    time.Time
+	sentTimeList []time.Time
+	limited      bool
+}
+
+// aggregator is combine notifications in window into one digest per channel, deduplicate identical notifications
+// and hold notifications over rate limit until rate limit allows
+type aggregator struct {
+	hostname          string
+	clock             clock
+	notifierContext   func() (*contexter.Notifier)
+	dispatch          func(s *sink, varList []string, subject string, body string)
+	mutex             *sync.Mutex
+	channelMap        map[string]*aggregateChannel
+	window            time.Duration
+	rateLimitCount    int
+	rateLimitInterval time.Duration
+}
+
+func (a *aggregator) add(sinkList []*sink, varList []string, subject string, body string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	now := a.clock.Now()
+	key := eventKey(varList)
+	renderedSubject := strings.NewReplacer(varList...).Replace(subject)
+	for _, s := range sinkList {
+		channel, ok := a.channelMap[s.name]
+		if !ok {
+			channel = &aggregateChannel{
+				eventMap: make(map[string]*aggregateEvent),
+			}
+			a.channelMap[s.name] = channel
+		}
+		// replace sink, because configuration may be changed
+		channel.sink = s
+		if len(channel.eventList) == 0 {
+			channel.firstTime = now
+		}
+		if event, ok := channel.eventMap[key]; ok {
+			event.count++
+			continue
+		}
+		event := &aggregateEvent{
+			key:             key,
+			varList:         varList,
+			subject:         subject,
+			body:            body,
+			renderedSubject: renderedSubject,
+			count:           1,
+		}
+		channel.eventList = append(channel.eventList, event)
+		channel.eventMap[key] = event
+	}
+}
+
+// allowance is number of messages channel can send now under rate limit, -1 means no limit
+func (a *aggregator) allowance(channel *aggregateChannel, now time.Time) (int) {
+	if a.rateLimitCount == 0 {
+		return -1
+	}
+	sentTimeList := channel.sentTimeList[:0]
+	for _, sentTime := range channel.sentTimeList {
+		if now.Sub(sentTime) < a.rateLimitInterval {
+			sentTimeList = append(sentTimeList, sentTime)
+		}
+	}
+	channel.sentTimeList = sentTimeList
+	if len(channel.sentTimeList) >= a.rateLimitCount {
+		return 0
+	}
+	return a.rateLimitCount - len(channel.sentTimeList)
+}
+
+// flushStructured is send one record per deduplicated notification, because digest loses variables.
+// every record is counted against rate limit, and records over rate limit are held back to be sent as digest
+func (a *aggregator) flushStructured(name string, channel *aggregateChannel, now time.Time, allowance int) {
+	sentCount := 0
+	for _, event := range channel.eventList {
+		if sentCount == allowance {
+			break
+		}
+		a.dispatch(channel.sink, event.varList, event.subject, event.body)
+		channel.sentTimeList = append(channel.sentTimeList, now)
+		delete(channel.eventMap, event.key)
+		sentCount++
+	}
+	channel.eventList = channel.eventList[sentCount:]
+	if len(channel.eventList) > 0 {
+		belog.Notice("notification of channel (%v) is rate limited", name)
+		channel.limited = true
+	}
+}
+
+// digestVarList is variables of digest. variable that has same value in all notifications keeps the value,
+// otherwise the value is "mixed". %(hostname) is hostname and %(count) is number of notifications
+func (a *aggregator) digestVarList(channel *aggregateChannel, total int) ([]string) {
+	variableList := make([]string, 0)
+	valueMap := make(map[string]string)
+	for i, event := range channel.eventList {
+		varMap := newVarMap(event.varList)
+		if i == 0 {
+			for j := 0; j + 1 < len(event.varList); j += 2 {
+				variableList = append(variableList, event.varList[j])
+				valueMap[event.varList[j]] = event.varList[j + 1]
+			}
+			continue
+		}
+		for _, variable := range variableList {
+			if value, ok := varMap[variable]; !ok || value != valueMap[variable] {
+				valueMap[variable] = digestMixedValue
+			}
+		}
+		for j := 0; j + 1 < len(event.varList); j += 2 {
+			if _, ok := valueMap[event.varList[j]]; !ok {
+				// missing in previous notifications
+				variableList = append(variableList, event.varList[j])
+				valueMap[event.varList[j]] = digestMixedValue
+			}
+		}
+	}
+	varList := []string{
+		"%(hostname)", a.hostname,
+		"%(count)", fmt.Sprintf("%v", total),
+	}
+	for _, variable := range variableList {
+		if variable == "%(hostname)" || variable == "%(count)" {
+			continue
+		}
+		varList = append(varList, variable, valueMap[variable])
+	}
+	return varList
+}
+
+func (a *aggregator) digest(channel *aggregateChannel) ([]string, string, string) {
+	if len(channel.eventList) == 1 && channel.eventList[0].count == 1 && !channel.limited {
+		event := channel.eventList[0]
+		return event.varList, event.subject, event.body
+	}
+	total := 0
+	for _, event := range channel.eventList {
+		total += event.count
+	}
+	varList := a.digestVarList(channel, total)
+	// events are rendered already, so digest is built without variables
+	subject := fmt.Sprintf("%v %v notifications", a.hostname, total)
+	body := fmt.Sprintf("hostname: %v\n%v notifications", a.hostname, total)
+	if channel.limited {
+		subject += " (rate limited)"
+		body += ", some of them were held back by rate limit"
+	}
+	body += "\n\n"
+	for _, event := range channel.eventList {
+		if event.count > 1 {
+			body += fmt.Sprintf("[x%v] ", event.count)
+		}
+		body += event.renderedSubject + "\n"
+	}
+	for i, event := range channel.eventList {
+		if i == maxDigestBodyCount {
+			body += fmt.Sprintf("\n-----\n(%v more notifications are omitted)\n", len(channel.eventList) - maxDigestBodyCount)
+			break
+		}
+		body += "\n-----\n" + strings.NewReplacer(event.varList...).Replace(event.body)
+	}
+	return varList, subject, body
+}
+
+// loadConfig is load window and rate limit from current context,
+// when aggregation is disabled or notifier is removed, they are 0 and pending notifications are drained
+func (a *aggregator) loadConfig() {
+	a.window = 0
+	a.rateLimitCount = 0
+	a.rateLimitInterval = defaultRateLimitInterval
+	notifierContext := a.notifierContext()
+	if notifierContext == nil {
+		return
+	}
+	a.window = time.Duration(notifierContext.AggregateWindow) * time.Second
+	a.rateLimitCount = int(notifierContext.RateLimitCount)
+	if notifierContext.RateLimitInterval > 0 {
+		a.rateLimitInterval = time.Duration(notifierContext.RateLimitInterval) * time.Second
+	}
+}
+
+// flush is send digest, or records of structured sink, of channels whose window has passed.
+// held back notifications of structured sink are sent as digest too
+func (a *aggregator) flush() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.loadConfig()
+	now := a.clock.Now()
+	for name, channel := range a.channelMap {
+		if len(channel.eventList) == 0 {
+			continue
+		}
+		if now.Sub(channel.firstTime) < a.window {
+			continue
+		}
+		allowance := a.allowance(channel, now)
+		if allowance == 0 {
+			if !channel.limited {
+				belog.Notice("notification of channel (%v) is rate limited", name)
+			}
+			channel.limited = true
+			continue
+		}
+		if channel.sink.structured && !channel.limited {
+			a.flushStructured(name, channel, now, allowance)
+			continue
+		}
+		varList, subject, body := a.digest(channel)
+		a.dispatch(channel.sink, varList, subject, body)
+		channel.sentTimeList = append(channel.sentTimeList, now)
+		channel.eventList = nil
+		channel.eventMap = make(map[string]*aggregateEvent)
+		channel.limited = false
+	}
+}
+
+func (a *aggregator) run() {
+	ticker := time.NewTicker(aggregatorTickInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		a.flush()
+	}
+}
+
+func newAggregator(hostname string, clock clock, notifierContext func() (*contexter.Notifier), dispatch func(s *sink, varList []string, subject string, body string)) (*aggregator) {
+	return &aggregator{
+		hostname:        hostname,
+		clock:           clock,
+		notifierContext: notifierContext,
+		dispatch:        dispatch,
+		mutex:           new(sync.Mutex),
+		channelMap:      make(map[string]*aggregateChannel),
+	}
+}
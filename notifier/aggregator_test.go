@@ -0,0 +1,304 @@
+package notifier
+
+import (
+	"github.com/potix/pdns-record-updater/contexter"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() (time.Time) {
+	return f.now
+}
+
+func (f *fakeClock) advance(duration time.Duration) {
+	f.now = f.now.Add(duration)
+}
+
+type dispatched struct {
+	sinkName string
+	varList  []string
+	subject  string
+	body     string
+}
+
+func newTestAggregator(notifierContext *contexter.Notifier) (*aggregator, *fakeClock, *[]*dispatched) {
+	clock := &fakeClock{ now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+	dispatchedList := make([]*dispatched, 0)
+	getNotifierContext := func() (*contexter.Notifier) {
+		return notifierContext
+	}
+	a := newAggregator("watcher1", clock, getNotifierContext, func(s *sink, varList []string, subject string, body string) {
+		dispatchedList = append(dispatchedList, &dispatched{ sinkName: s.name, varList: varList, subject: subject, body: body })
+	})
+	return a, clock, &dispatchedList
+}
+
+func newTestSinkList() ([]*sink) {
+	return []*sink{
+		&sink{ name: "mail/0", notifierType: "mail" },
+		&sink{ name: "webhook/0", notifierType: "webhook" },
+	}
+}
+
+func newTestEventVarList(name string, newAlive string, t string) ([]string) {
+	return []string{
+		"%(time)", t,
+		"%(domain)", "example.com",
+		"%(groupName)", "group1",
+		"%(name)", name,
+		"%(type)", "A",
+		"%(content)", "192.0.2.1",
+		"%(newAlive)", newAlive,
+		"%(detail)", "detail at " + t,
+	}
+}
+
+const testSubject = "%(name) %(content): new alive = %(newAlive)"
+
+func TestAggregatorWindow(t *testing.T) {
+	notifierContext := &contexter.Notifier{ AggregateWindow: 10 }
+	a, clock, dispatchedList := newTestAggregator(notifierContext)
+	sinkList := newTestSinkList()
+	a.add(sinkList, newTestEventVarList("www", "false", "t1"), testSubject, "body")
+	clock.advance(5 * time.Second)
+	a.add(sinkList, newTestEventVarList("api", "false", "t2"), testSubject, "body")
+	a.flush()
+	if len(*dispatchedList) != 0 {
+		t.Fatalf("dispatched before window has passed: %v", len(*dispatchedList))
+	}
+	clock.advance(5 * time.Second)
+	a.flush()
+	if len(*dispatchedList) != len(sinkList) {
+		t.Fatalf("dispatch count = %v, want one digest per channel (%v)", len(*dispatchedList), len(sinkList))
+	}
+	for _, d := range *dispatchedList {
+		if !strings.Contains(d.subject, "2 notifications") {
+			t.Errorf("%v: subject = %v", d.sinkName, d.subject)
+		}
+		if !strings.Contains(d.body, "www 192.0.2.1: new alive = false") || !strings.Contains(d.body, "api 192.0.2.1: new alive = false") {
+			t.Errorf("%v: body = %v", d.sinkName, d.body)
+		}
+	}
+	a.flush()
+	if len(*dispatchedList) != len(sinkList) {
+		t.Errorf("dispatched again without new notification")
+	}
+}
+
+func TestAggregatorDeduplicate(t *testing.T) {
+	notifierContext := &contexter.Notifier{ AggregateWindow: 10 }
+	a, clock, dispatchedList := newTestAggregator(notifierContext)
+	sinkList := newTestSinkList()[:1]
+	// time and detail differ, but notification is identical
+	a.add(sinkList, newTestEventVarList("www", "false", "t1"), testSubject, "body")
+	clock.advance(time.Second)
+	a.add(sinkList, newTestEventVarList("www", "false", "t2"), testSubject, "body")
+	clock.advance(time.Second)
+	a.add(sinkList, newTestEventVarList("www", "true", "t3"), testSubject, "body")
+	clock.advance(10 * time.Second)
+	a.flush()
+	if len(*dispatchedList) != 1 {
+		t.Fatalf("dispatch count = %v, want 1", len(*dispatchedList))
+	}
+	d := (*dispatchedList)[0]
+	if !strings.Contains(d.body, "[x2] www 192.0.2.1: new alive = false") {
+		t.Errorf("duplicated notification is not combined: %v", d.body)
+	}
+	if !strings.Contains(d.body, "\nwww 192.0.2.1: new alive = true") {
+		t.Errorf("notification of other alive is combined: %v", d.body)
+	}
+}
+
+func TestAggregatorRateLimit(t *testing.T) {
+	notifierContext := &contexter.Notifier{ RateLimitCount: 1, RateLimitInterval: 60 }
+	a, clock, dispatchedList := newTestAggregator(notifierContext)
+	sinkList := newTestSinkList()[:1]
+	a.add(sinkList, newTestEventVarList("www", "false", "t1"), testSubject, "body")
+	a.flush()
+	if len(*dispatchedList) != 1 {
+		t.Fatalf("dispatch count = %v, want 1", len(*dispatchedList))
+	}
+	clock.advance(10 * time.Second)
+	a.add(sinkList, newTestEventVarList("api", "false", "t2"), testSubject, "body")
+	a.add(sinkList, newTestEventVarList("ftp", "false", "t3"), testSubject, "body")
+	a.flush()
+	if len(*dispatchedList) != 1 {
+		t.Fatalf("dispatched over rate limit: %v", len(*dispatchedList))
+	}
+	clock.advance(50 * time.Second)
+	a.flush()
+	if len(*dispatchedList) != 2 {
+		t.Fatalf("held back notifications are not sent after rate limit reset: %v", len(*dispatchedList))
+	}
+	d := (*dispatchedList)[1]
+	if !strings.Contains(d.subject, "2 notifications (rate limited)") {
+		t.Errorf("subject = %v", d.subject)
+	}
+	if !strings.Contains(d.body, "api 192.0.2.1") || !strings.Contains(d.body, "ftp 192.0.2.1") {
+		t.Errorf("body = %v", d.body)
+	}
+}
+
+func TestAggregatorPassThrough(t *testing.T) {
+	notifierContext := &contexter.Notifier{}
+	a, _, dispatchedList := newTestAggregator(notifierContext)
+	sinkList := newTestSinkList()[:1]
+	for i, name := range []string{ "www", "api" } {
+		varList := newTestEventVarList(name, "false", "t1")
+		a.add(sinkList, varList, testSubject, "body")
+		a.flush()
+		if len(*dispatchedList) != i + 1 {
+			t.Fatalf("dispatch count = %v, want %v", len(*dispatchedList), i + 1)
+		}
+		d := (*dispatchedList)[i]
+		if d.subject != testSubject || d.body != "body" || len(d.varList) != len(varList) {
+			t.Errorf("notification is changed: %v %v", d.subject, d.body)
+		}
+	}
+}
+
+func TestAggregatorStructuredSink(t *testing.T) {
+	notifierContext := &contexter.Notifier{ AggregateWindow: 10 }
+	a, clock, dispatchedList := newTestAggregator(notifierContext)
+	sinkList := []*sink{ &sink{ name: "syslog/0", notifierType: "syslog", structured: true } }
+	a.add(sinkList, newTestEventVarList("www", "false", "t1"), testSubject, "body")
+	a.add(sinkList, newTestEventVarList("www", "false", "t2"), testSubject, "body")
+	a.add(sinkList, newTestEventVarList("api", "false", "t3"), testSubject, "body")
+	clock.advance(10 * time.Second)
+	a.flush()
+	if len(*dispatchedList) != 2 {
+		t.Fatalf("dispatch count = %v, want one record per deduplicated notification (2)", len(*dispatchedList))
+	}
+	for i, name := range []string{ "www", "api" } {
+		varMap := newVarMap((*dispatchedList)[i].varList)
+		if varMap["%(name)"] != name || varMap["%(domain)"] != "example.com" {
+			t.Errorf("variables are lost: %v", (*dispatchedList)[i].varList)
+		}
+	}
+	if sentCount := len(a.channelMap["syslog/0"].sentTimeList); sentCount != 2 {
+		t.Errorf("sent count = %v, want every record to be counted (2)", sentCount)
+	}
+}
+
+func TestAggregatorStructuredSinkRateLimit(t *testing.T) {
+	notifierContext := &contexter.Notifier{ RateLimitCount: 2, RateLimitInterval: 60 }
+	a, clock, dispatchedList := newTestAggregator(notifierContext)
+	sinkList := []*sink{ &sink{ name: "syslog/0", notifierType: "syslog", structured: true } }
+	for i, name := range []string{ "www", "api", "ftp", "smtp" } {
+		a.add(sinkList, newTestEventVarList(name, "false", fmt.Sprintf("t%v", i)), testSubject, "body")
+	}
+	a.flush()
+	if len(*dispatchedList) != 2 {
+		t.Fatalf("dispatch count = %v, want records up to rate limit (2)", len(*dispatchedList))
+	}
+	for i, name := range []string{ "www", "api" } {
+		if varMap := newVarMap((*dispatchedList)[i].varList); varMap["%(name)"] != name {
+			t.Errorf("record %v: name = %v, want %v", i, varMap["%(name)"], name)
+		}
+	}
+	clock.advance(30 * time.Second)
+	a.add(sinkList, newTestEventVarList("pop", "false", "t4"), testSubject, "body")
+	a.flush()
+	if len(*dispatchedList) != 2 {
+		t.Fatalf("dispatched over rate limit: %v", len(*dispatchedList))
+	}
+	clock.advance(30 * time.Second)
+	a.flush()
+	if len(*dispatchedList) != 3 {
+		t.Fatalf("held back records are not sent after rate limit reset: %v", len(*dispatchedList))
+	}
+	d := (*dispatchedList)[2]
+	if !strings.Contains(d.subject, "3 notifications (rate limited)") {
+		t.Errorf("subject = %v", d.subject)
+	}
+	for _, name := range []string{ "ftp", "smtp", "pop" } {
+		if !strings.Contains(d.body, name + " 192.0.2.1") {
+			t.Errorf("%v is not in summary: %v", name, d.body)
+		}
+	}
+	if sentCount := len(a.channelMap["syslog/0"].sentTimeList); sentCount != 1 {
+		t.Errorf("sent count = %v, want 1", sentCount)
+	}
+}
+
+func TestAggregatorDigestWebhook(t *testing.T) {
+	var requestBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestBody, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+	notifierContext := &contexter.Notifier{
+		WebhookList: []*contexter.Webhook{
+			&contexter.Webhook{
+				URL:     server.URL,
+				Body:    "{\"subject\":\"%(subject)\",\"count\":\"%(count)\",\"domain\":\"%(domain)\",\"name\":\"%(name)\",\"newAlive\":\"%(newAlive)\",\"targetName\":\"%(targetName)\"}",
+				Timeout: 5,
+			},
+		},
+		AggregateWindow: 10,
+	}
+	clock := &fakeClock{ now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+	a := newAggregator("watcher1", clock, func() (*contexter.Notifier) { return notifierContext }, func(s *sink, varList []string, subject string, body string) {
+		if err := s.send(varList, subject, body); err != nil {
+			t.Errorf("can not send: %v", err)
+		}
+	})
+	sinkList := (&Notifier{ hostname: "watcher1" }).sinkList(notifierContext)
+	a.add(sinkList, newTestEventVarList("www", "false", "t1"), testSubject, "body")
+	// targetName is only in second notification
+	a.add(sinkList, append(newTestEventVarList("api", "false", "t2"), "%(targetName)", "target1"), testSubject, "body")
+	clock.advance(10 * time.Second)
+	a.flush()
+	var payload map[string]string
+	if err := json.Unmarshal(requestBody, &payload); err != nil {
+		t.Fatalf("body is not json (%v): %v", string(requestBody), err)
+	}
+	expectedPayload := map[string]string{
+		"subject":    "watcher1 2 notifications",
+		"count":      "2",
+		"domain":     "example.com",
+		"name":       "mixed",
+		"newAlive":   "false",
+		"targetName": "mixed",
+	}
+	for key, value := range expectedPayload {
+		if payload[key] != value {
+			t.Errorf("%v = %q, want %q", key, payload[key], value)
+		}
+	}
+}
+
+func TestAggregatorConfigChange(t *testing.T) {
+	notifierContext := &contexter.Notifier{ AggregateWindow: 60 }
+	a, clock, dispatchedList := newTestAggregator(notifierContext)
+	sinkList := newTestSinkList()[:1]
+	a.add(sinkList, newTestEventVarList("www", "false", "t1"), testSubject, "body")
+	a.add(sinkList, newTestEventVarList("api", "false", "t2"), testSubject, "body")
+	clock.advance(10 * time.Second)
+	// window is shortened by reload
+	notifierContext.AggregateWindow = 10
+	a.flush()
+	if len(*dispatchedList) != 1 {
+		t.Fatalf("dispatch count = %v, want 1", len(*dispatchedList))
+	}
+	a.add(sinkList, newTestEventVarList("ftp", "false", "t3"), testSubject, "body")
+	// aggregation is disabled by reload, queued notification is drained
+	notifierContext.AggregateWindow = 0
+	a.flush()
+	if len(*dispatchedList) != 2 {
+		t.Fatalf("queued notification is not drained: %v", len(*dispatchedList))
+	}
+	if d := (*dispatchedList)[1]; d.subject != testSubject {
+		t.Errorf("subject = %v, want %v", d.subject, testSubject)
+	}
+}
@@ -0,0 +1,143 @@
+package notifier
+
+import (
+	"github.com/pkg/errors"
+	"github.com/potix/pdns-record-updater/contexter"
+	"encoding/json"
+	"strings"
+	"time"
+	"fmt"
+)
+
+const (
+	chatColorAlive   = "#2eb886"
+	chatColorDown    = "#a30200"
+	chatColorWarning = "#daa038"
+	chatColorNeutral = "#808080"
+)
+
+type chatField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type chatAttachment struct {
+	Fallback string       `json:"fallback"`
+	Color    string       `json:"color"`
+	Title    string       `json:"title"`
+	Text     string       `json:"text,omitempty"`
+	Fields   []*chatField `json:"fields,omitempty"`
+	Footer   string       `json:"footer,omitempty"`
+	Ts       int64        `json:"ts"`
+}
+
+type chatPayload struct {
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	IconEmoji   string            `json:"icon_emoji,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []*chatAttachment `json:"attachments"`
+}
+
+func newVarMap(varList []string) (map[string]string) {
+	varMap := make(map[string]string, len(varList) / 2)
+	for i := 0; i + 1 < len(varList); i += 2 {
+		varMap[varList[i]] = varList[i + 1]
+	}
+	return varMap
+}
+
+// chatColor is color of attachment, alive is green, down is red, and flapping or mixed digest is yellow
+func chatColor(varMap map[string]string) (string) {
+	if varMap["%(flapping)"] == "true" || varMap["%(newAlive)"] == digestMixedValue {
+		return chatColorWarning
+	}
+	alive, ok := varMap["%(newAlive)"]
+	if !ok {
+		alive, ok = varMap["%(alive)"]
+	}
+	if !ok {
+		return chatColorNeutral
+	}
+	if alive == "true" {
+		return chatColorAlive
+	}
+	return chatColorDown
+}
+
+// chatTargetFields is parse target result lines ("domain groupName name type content targetName dest alive (detail)") into fields
+func chatTargetFields(varMap map[string]string) ([]*chatField) {
+	prefix := strings.Join([]string{
+		varMap["%(domain)"],
+		varMap["%(groupName)"],
+		varMap["%(name)"],
+		varMap["%(type)"],
+		varMap["%(content)"],
+	}, " ") + " "
+	fields := make([]*chatField, 0)
+	for _, line := range strings.Split(varMap["%(detail)"], "\n") {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		targetInfo := strings.SplitN(strings.TrimPrefix(line, prefix), " ", 2)
+		if len(targetInfo) != 2 {
+			continue
+		}
+		fields = append(fields, &chatField{
+			Title: "target " + targetInfo[0],
+			Value: targetInfo[1],
+			Short: false,
+		})
+	}
+	return fields
+}
+
+func newChatPayload(chatContext *contexter.Chat, varList []string, subject string, body string, now time.Time) (*chatPayload) {
+	varMap := newVarMap(varList)
+	replacer := strings.NewReplacer(varList...)
+	renderedSubject := replacer.Replace(subject)
+	attachment := &chatAttachment{
+		Fallback: renderedSubject,
+		Color:    chatColor(varMap),
+		Title:    renderedSubject,
+		Footer:   varMap["%(hostname)"],
+		Ts:       now.Unix(),
+	}
+	// digest has %(count), its variables may be mixed, so body is shown instead of fields
+	_, isDigest := varMap["%(count)"]
+	if domain, ok := varMap["%(domain)"]; ok && !isDigest {
+		attachment.Fields = []*chatField{
+			&chatField{ Title: "domain", Value: domain, Short: true },
+			&chatField{ Title: "group", Value: varMap["%(groupName)"], Short: true },
+			&chatField{ Title: "record", Value: fmt.Sprintf("%v %v %v", varMap["%(name)"], varMap["%(type)"], varMap["%(content)"]), Short: false },
+			&chatField{ Title: "alive", Value: fmt.Sprintf("%v -> %v", varMap["%(oldAlive)"], varMap["%(newAlive)"]), Short: true },
+		}
+		attachment.Fields = append(attachment.Fields, chatTargetFields(varMap)...)
+	} else {
+		// not notification of dynamic record (e.g. root cause) or digest
+		attachment.Text = replacer.Replace(body)
+	}
+	return &chatPayload{
+		Channel:     chatContext.Channel,
+		Username:    chatContext.Username,
+		IconEmoji:   chatContext.IconEmoji,
+		Text:        renderedSubject,
+		Attachments: []*chatAttachment{ attachment },
+	}
+}
+
+func (n *Notifier) sendChat(chatContext *contexter.Chat, varList []string, subject string, body string) (error) {
+	payload, err := json.Marshal(newChatPayload(chatContext, varList, subject, body, time.Now()))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("can not marshal chat payload (%v)", chatContext.URL))
+	}
+	webhookContext := &contexter.Webhook{
+		URL:           chatContext.URL,
+		TLSSkipVerify: chatContext.TLSSkipVerify,
+		Timeout:       chatContext.Timeout,
+		Retry:         chatContext.Retry,
+		RetryWait:     chatContext.RetryWait,
+	}
+	return n.postWebhook(webhookContext, string(payload))
+}
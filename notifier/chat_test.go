@@ -0,0 +1,94 @@
+package notifier
+
+import (
+	"github.com/potix/pdns-record-updater/contexter"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestVarList(content string, newAlive string, flapping string, detail string) ([]string) {
+	return []string{
+		"%(hostname)", "watcher1",
+		"%(domain)", "example.com",
+		"%(groupName)", "group1",
+		"%(name)", "www",
+		"%(type)", "TXT",
+		"%(content)", content,
+		"%(oldAlive)", "true",
+		"%(newAlive)", newAlive,
+		"%(flapping)", flapping,
+		"%(detail)", detail,
+	}
+}
+
+func sendTestChat(t *testing.T, varList []string) (*chatPayload) {
+	payload := new(chatPayload)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+			t.Errorf("can not decode payload: %v", err)
+		}
+	}))
+	defer server.Close()
+	chatContext := &contexter.Chat{
+		URL:      server.URL,
+		Channel:  "#alert",
+		Username: "pdns-record-updater",
+		Timeout:  5,
+	}
+	if err := new(Notifier).sendChat(chatContext, varList, "%(name) %(content): new alive = %(newAlive)", "body"); err != nil {
+		t.Fatalf("can not send chat: %v", err)
+	}
+	return payload
+}
+
+func TestSendChatColor(t *testing.T) {
+	testCaseList := []struct {
+		newAlive string
+		flapping string
+		color    string
+	}{
+		{ newAlive: "true",  flapping: "false", color: chatColorAlive },
+		{ newAlive: "false", flapping: "false", color: chatColorDown },
+		{ newAlive: "false", flapping: "true",  color: chatColorWarning },
+	}
+	for _, testCase := range testCaseList {
+		payload := sendTestChat(t, newTestVarList("1.2.3.4", testCase.newAlive, testCase.flapping, ""))
+		if payload.Channel != "#alert" || payload.Username != "pdns-record-updater" {
+			t.Errorf("channel = %v, username = %v", payload.Channel, payload.Username)
+		}
+		if len(payload.Attachments) != 1 {
+			t.Fatalf("attachment count = %v, want 1", len(payload.Attachments))
+		}
+		if color := payload.Attachments[0].Color; color != testCase.color {
+			t.Errorf("newAlive = %v, flapping = %v: color = %v, want %v", testCase.newAlive, testCase.flapping, color, testCase.color)
+		}
+	}
+}
+
+func TestSendChatTargetFields(t *testing.T) {
+	content := "\"v=spf1 include:example.net -all\""
+	detail := "example.com group1 www TXT " + content + " target1 192.0.2.1 true\n" +
+		"example.com group1 www TXT " + content + " target2 192.0.2.2 false (timeout: no response)\n" +
+		"example.com group1 www TXT " + content + " target3 (no dest) false\n"
+	payload := sendTestChat(t, newTestVarList(content, "false", "false", detail))
+	if payload.Text != "www " + content + ": new alive = false" {
+		t.Errorf("text = %v", payload.Text)
+	}
+	expectedFieldMap := map[string]string{
+		"target target1": "192.0.2.1 true",
+		"target target2": "192.0.2.2 false (timeout: no response)",
+		"target target3": "(no dest) false",
+		"record":         "www TXT " + content,
+	}
+	fieldMap := make(map[string]string)
+	for _, field := range payload.Attachments[0].Fields {
+		fieldMap[field.Title] = field.Value
+	}
+	for title, value := range expectedFieldMap {
+		if fieldMap[title] != value {
+			t.Errorf("field (%v) = %q, want %q", title, fieldMap[title], value)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package notifier
+
+import (
+	"github.com/pkg/errors"
+	"github.com/potix/pdns-record-updater/contexter"
+	"encoding/binary"
+	"bytes"
+	"net"
+	"strings"
+	"fmt"
+)
+
+const (
+	defaultJournaldSocketPath = "/run/systemd/journal/socket"
+	defaultJournaldIdentifier = "pdns-record-updater"
+)
+
+// appendJournalField is append field of journald native protocol, value including newline is serialized as binary
+func appendJournalField(buffer *bytes.Buffer, name string, value string) {
+	if !strings.Contains(value, "\n") {
+		buffer.WriteString(name + "=" + value + "\n")
+		return
+	}
+	buffer.WriteString(name + "\n")
+	binary.Write(buffer, binary.LittleEndian, uint64(len(value)))
+	buffer.WriteString(value + "\n")
+}
+
+func formatJournalMessage(journaldContext *contexter.Journald, varList []string, subject string) ([]byte) {
+	varMap := newVarMap(varList)
+	identifier := journaldContext.Identifier
+	if identifier == "" {
+		identifier = defaultJournaldIdentifier
+	}
+	buffer := new(bytes.Buffer)
+	appendJournalField(buffer, "MESSAGE", strings.NewReplacer(varList...).Replace(subject))
+	appendJournalField(buffer, "PRIORITY", fmt.Sprintf("%v", notifySeverity(varMap)))
+	appendJournalField(buffer, "SYSLOG_IDENTIFIER", identifier)
+	for _, field := range notifyFieldList {
+		if value, ok := varMap[field.variable]; ok {
+			appendJournalField(buffer, field.journalName, value)
+		}
+	}
+	if detail, ok := varMap["%(detail)"]; ok && detail != "" {
+		appendJournalField(buffer, "PDNS_DETAIL", detail)
+	}
+	return buffer.Bytes()
+}
+
+func (n *Notifier) sendJournald(journaldContext *contexter.Journald, varList []string, subject string) (error) {
+	socketPath := journaldContext.SocketPath
+	if socketPath == "" {
+		socketPath = defaultJournaldSocketPath
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{ Name: socketPath, Net: "unixgram" })
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("can not connect journald (%v)", socketPath))
+	}
+	defer conn.Close()
+	if _, err := conn.Write(formatJournalMessage(journaldContext, varList, subject)); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("can not write journal message (%v)", socketPath))
+	}
+	return nil
+}
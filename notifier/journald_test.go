@@ -0,0 +1,125 @@
+package notifier
+
+import (
+	"github.com/potix/pdns-record-updater/contexter"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type journalField struct {
+	name   string
+	value  string
+	binary bool
+}
+
+// parseJournalMessage is parse message of journald native protocol
+func parseJournalMessage(t *testing.T, message []byte) ([]*journalField) {
+	fieldList := make([]*journalField, 0)
+	for len(message) > 0 {
+		lineEnd := bytes.IndexByte(message, '\n')
+		if lineEnd < 0 {
+			t.Fatalf("field is not terminated: %q", message)
+		}
+		line := message[:lineEnd]
+		message = message[lineEnd + 1:]
+		if i := bytes.IndexByte(line, '='); i >= 0 {
+			fieldList = append(fieldList, &journalField{ name: string(line[:i]), value: string(line[i + 1:]) })
+			continue
+		}
+		if len(message) < 8 {
+			t.Fatalf("no size of binary field (%v)", string(line))
+		}
+		size := binary.LittleEndian.Uint64(message[:8])
+		message = message[8:]
+		if uint64(len(message)) < size + 1 || message[size] != '\n' {
+			t.Fatalf("binary field (%v) is broken: %q", string(line), message)
+		}
+		fieldList = append(fieldList, &journalField{ name: string(line), value: string(message[:size]), binary: true })
+		message = message[size + 1:]
+	}
+	return fieldList
+}
+
+func TestFormatJournalMessage(t *testing.T) {
+	testCaseList := []struct {
+		name            string
+		journaldContext *contexter.Journald
+		varList         []string
+		subject         string
+		expected        []*journalField
+	}{
+		{
+			name:            "default",
+			journaldContext: &contexter.Journald{},
+			varList:         []string{ "%(name)", "www", "%(newAlive)", "false", "%(detail)", "" },
+			subject:         "%(name) down",
+			expected:        []*journalField{
+				&journalField{ name: "MESSAGE", value: "www down" },
+				&journalField{ name: "PRIORITY", value: "4" },
+				&journalField{ name: "SYSLOG_IDENTIFIER", value: "pdns-record-updater" },
+				&journalField{ name: "PDNS_NAME", value: "www" },
+				&journalField{ name: "PDNS_NEW_ALIVE", value: "false" },
+			},
+		},
+		{
+			name:            "multi-line",
+			journaldContext: &contexter.Journald{ Identifier: "pdru" },
+			varList:         []string{ "%(content)", "a=b", "%(alive)", "true", "%(detail)", "line1\nline2\n" },
+			subject:         "first\nsecond",
+			expected:        []*journalField{
+				&journalField{ name: "MESSAGE", value: "first\nsecond", binary: true },
+				&journalField{ name: "PRIORITY", value: "5" },
+				&journalField{ name: "SYSLOG_IDENTIFIER", value: "pdru" },
+				&journalField{ name: "PDNS_CONTENT", value: "a=b" },
+				&journalField{ name: "PDNS_ALIVE", value: "true" },
+				&journalField{ name: "PDNS_DETAIL", value: "line1\nline2\n", binary: true },
+			},
+		},
+	}
+	for _, testCase := range testCaseList {
+		fieldList := parseJournalMessage(t, formatJournalMessage(testCase.journaldContext, testCase.varList, testCase.subject))
+		if len(fieldList) != len(testCase.expected) {
+			t.Errorf("%v: field count = %v, want %v", testCase.name, len(fieldList), len(testCase.expected))
+			continue
+		}
+		for i, expected := range testCase.expected {
+			if *fieldList[i] != *expected {
+				t.Errorf("%v: field %v = %+v, want %+v", testCase.name, i, *fieldList[i], *expected)
+			}
+		}
+	}
+}
+
+func TestSendJournald(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journald")
+	if err != nil {
+		t.Fatalf("can not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{ Name: socketPath, Net: "unixgram" })
+	if err != nil {
+		t.Skipf("can not listen unixgram: %v", err)
+	}
+	defer conn.Close()
+	journaldContext := &contexter.Journald{ SocketPath: socketPath }
+	varList := []string{ "%(name)", "www", "%(detail)", "line1\nline2" }
+	if err := new(Notifier).sendJournald(journaldContext, varList, "%(name) down"); err != nil {
+		t.Fatalf("can not send journal message: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	buffer := make([]byte, 4096)
+	size, err := conn.Read(buffer)
+	if err != nil {
+		t.Fatalf("can not receive: %v", err)
+	}
+	if !bytes.Equal(buffer[:size], formatJournalMessage(journaldContext, varList, "%(name) down")) {
+		t.Errorf("message = %q", buffer[:size])
+	}
+}
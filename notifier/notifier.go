@@ -4,12 +4,14 @@ import (
 	"github.com/pkg/errors"
 	"github.com/potix/belog"
 	"github.com/potix/pdns-record-updater/contexter"
+	"github.com/potix/pdns-record-updater/metrics"
 	"net"
 	"crypto/tls"
 	"net/mail"
 	"net/smtp"
 	"strings"
 	"os"
+	"sync"
 	"fmt"
 )
 
@@ -17,15 +19,17 @@ import (
 type Notifier struct {
 	hostname string
 	context *contexter.Context
+	aggregator      *aggregator
+	startAggregator sync.Once
 }
 
-func (n *Notifier) sendMail(mailContext *contexter.Mail, replacer *strings.Replacer, subject string, body string) {
+func (n *Notifier) sendMail(mailContext *contexter.Mail, replacer *strings.Replacer, subject string, body string) (error) {
 	from := mail.Address{
 		Address: mailContext.From,
 	}
 	toList, err := mail.ParseAddressList(mailContext.To)
 	if err != nil {
-		belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not parse mail address list (%v)", mailContext.To)))
+		return errors.Wrap(err, fmt.Sprintf("can not parse mail address list (%v)", mailContext.To))
 	}
 	message := ""
 	message += fmt.Sprintf("From: %s\r\n", mailContext.From)
@@ -52,22 +56,19 @@ func (n *Notifier) sendMail(mailContext *contexter.Mail, replacer *strings.Repla
 		}
 		conn, err = tls.Dial("tcp", mailContext.HostPort, tlsContext)
 		if err != nil {
-			belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not connect mail host with tls (%v)", mailContext.HostPort)))
-			return
+			return errors.Wrap(err, fmt.Sprintf("can not connect mail host with tls (%v)", mailContext.HostPort))
 		}
 	} else {
 		conn, err = net.Dial("tcp", mailContext.HostPort)
 		if err != nil {
-			belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not connect mail host (%v)", mailContext.HostPort)))
-			return
+			return errors.Wrap(err, fmt.Sprintf("can not connect mail host (%v)", mailContext.HostPort))
 		}
 	}
 	defer conn.Close()
 
 	client, err := smtp.NewClient(conn, host)
 	if err != nil {
-		belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not create smtp client (%v)", mailContext.HostPort)))
-		return
+		return errors.Wrap(err, fmt.Sprintf("can not create smtp client (%v)", mailContext.HostPort))
 	}
 
 	if mailContext.UseStartTLS {
@@ -76,21 +77,18 @@ func (n *Notifier) sendMail(mailContext *contexter.Mail, replacer *strings.Repla
 			InsecureSkipVerify: mailContext.TLSSkipVerify,
 		}
 		if err := client.StartTLS(tlsconfig); err != nil {
-			belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not start tls (%v)", mailContext.HostPort)))
-			return
+			return errors.Wrap(err, fmt.Sprintf("can not start tls (%v)", mailContext.HostPort))
 		}
 	}
 
 	if auth != nil {
 		if err = client.Auth(auth); err != nil {
-			belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not authentication (%v) (%v)", mailContext.Username, mailContext.Password)))
-			return
+			return errors.Wrap(err, fmt.Sprintf("can not authentication (%v) (%v)", mailContext.Username, mailContext.Password))
 		}
 	}
 
 	if err = client.Mail(from.Address); err != nil {
-		belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not send MAIL command (%v)", from.Address)))
-		return
+		return errors.Wrap(err, fmt.Sprintf("can not send MAIL command (%v)", from.Address))
 	}
 
 	var emails []string
@@ -99,44 +97,127 @@ func (n *Notifier) sendMail(mailContext *contexter.Mail, replacer *strings.Repla
 	}
 	recept := strings.Join(emails, ",")
 	if err = client.Rcpt(recept); err != nil {
-		belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not send RCPT command (%v)", recept)))
-		return
+		return errors.Wrap(err, fmt.Sprintf("can not send RCPT command (%v)", recept))
 	}
 
 	w, err := client.Data()
 	if err != nil {
-		belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not send DATA command")))
-		return
+		return errors.Wrap(err, fmt.Sprintf("can not send DATA command"))
 	}
 
 	_, err = w.Write([]byte(message))
 	if err != nil {
-		belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not write message (%v)", message)))
-		return
+		return errors.Wrap(err, fmt.Sprintf("can not write message (%v)", message))
 	}
 
 	err = w.Close()
 	if err != nil {
-		belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not close message writer")))
-		return
+		return errors.Wrap(err, fmt.Sprintf("can not close message writer"))
 	}
 
 	err = client.Quit()
 	if err != nil {
 		belog.Notice("%v", errors.Wrap(err, fmt.Sprintf("can not send QUIT command")))
-		return
 	}
+	return nil
+}
+
+// sink is destination of notification, structured sink uses variables of each notification,
+// so it is sent one record per notification instead of digest
+type sink struct {
+	name         string
+	notifierType string
+	structured   bool
+	send         func(varList []string, subject string, body string) (error)
 }
 
-// Notify is Notify
-func (n *Notifier) Notify(replacer *strings.Replacer, subject string, body string) {
+func (n *Notifier) sinkList(notifierContext *contexter.Notifier) ([]*sink) {
+	sinkList := make([]*sink, 0)
+	for i, mailContext := range notifierContext.MailList {
+		mailContext := mailContext
+		sinkList = append(sinkList, &sink{
+			name:         fmt.Sprintf("mail/%v", i),
+			notifierType: "mail",
+			send:         func(varList []string, subject string, body string) (error) {
+				return n.sendMail(mailContext, strings.NewReplacer(varList...), subject, body)
+			},
+		})
+	}
+	for i, webhookContext := range notifierContext.WebhookList {
+		webhookContext := webhookContext
+		sinkList = append(sinkList, &sink{
+			name:         fmt.Sprintf("webhook/%v", i),
+			notifierType: "webhook",
+			send:         func(varList []string, subject string, body string) (error) {
+				return n.sendWebhook(webhookContext, varList, subject, body)
+			},
+		})
+	}
+	for i, chatContext := range notifierContext.ChatList {
+		chatContext := chatContext
+		sinkList = append(sinkList, &sink{
+			name:         fmt.Sprintf("chat/%v", i),
+			notifierType: "chat",
+			send:         func(varList []string, subject string, body string) (error) {
+				return n.sendChat(chatContext, varList, subject, body)
+			},
+		})
+	}
+	for i, syslogContext := range notifierContext.SyslogList {
+		syslogContext := syslogContext
+		sinkList = append(sinkList, &sink{
+			name:         fmt.Sprintf("syslog/%v", i),
+			notifierType: "syslog",
+			structured:   true,
+			send:         func(varList []string, subject string, body string) (error) {
+				return n.sendSyslog(syslogContext, varList, subject)
+			},
+		})
+	}
+	for i, journaldContext := range notifierContext.JournaldList {
+		journaldContext := journaldContext
+		sinkList = append(sinkList, &sink{
+			name:         fmt.Sprintf("journald/%v", i),
+			notifierType: "journald",
+			structured:   true,
+			send:         func(varList []string, subject string, body string) (error) {
+				return n.sendJournald(journaldContext, varList, subject)
+			},
+		})
+	}
+	return sinkList
+}
+
+func (n *Notifier) dispatch(s *sink, varList []string, subject string, body string) {
+	go func() {
+		err := s.send(varList, subject, body)
+		if err != nil {
+			belog.Error("%v", err)
+		}
+		metrics.CountNotification(s.notifierType, err)
+	}()
+}
+
+// Notify is notify to mail, webhook, chat, syslog and journald, varList is pairs of variable and value (e.g. "%(hostname)", hostname) used in templates.
+// when aggregateWindow or rateLimitCount is configured, notifications are passed to aggregator
+func (n *Notifier) Notify(varList []string, subject string, body string) {
 	notifierContext := n.context.GetNotifier()
-	if notifierContext == nil || notifierContext.MailList == nil {
+	if notifierContext == nil {
 		return
 	}
-	for _, mailContext := range notifierContext.MailList {
-		go n.sendMail(mailContext, replacer, subject, body)
+	sinkList := n.sinkList(notifierContext)
+	if notifierContext.AggregateWindow == 0 && notifierContext.RateLimitCount == 0 {
+		// drain notifications queued while aggregation was enabled
+		n.aggregator.flush()
+		for _, s := range sinkList {
+			n.dispatch(s, varList, subject, body)
+		}
+		return
 	}
+	n.startAggregator.Do(func() {
+		go n.aggregator.run()
+	})
+	n.aggregator.add(sinkList, varList, subject, body)
 }
 
 // New is create notifier
@@ -145,8 +226,10 @@ func New(context *contexter.Context) (n *Notifier) {
 	if err != nil {
 		hostname = "unknown"
 	}
-	return &Notifier{
+	n = &Notifier{
 		hostname : hostname,
 		context: context,
 	}
+	n.aggregator = newAggregator(hostname, realClock{}, context.GetNotifier, n.dispatch)
+	return n
 }
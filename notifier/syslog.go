@@ -0,0 +1,167 @@
+package notifier
+
+import (
+	"github.com/pkg/errors"
+	"github.com/potix/pdns-record-updater/contexter"
+	"github.com/potix/pdns-record-updater/helper"
+	"crypto/tls"
+	"net"
+	"os"
+	"strings"
+	"time"
+	"fmt"
+)
+
+const (
+	defaultSyslogUnixAddress      = "/dev/log"
+	defaultSyslogFacility         = "daemon"
+	defaultSyslogAppName          = "pdns-record-updater"
+	// 32473 is private enterprise number for documentation (rfc5612)
+	defaultSyslogStructuredDataID = "pdns@32473"
+)
+
+type notifyField struct {
+	variable    string
+	sdName      string
+	journalName string
+}
+
+// notifyFieldList is variables emitted as structured fields of syslog and journald
+var notifyFieldList = []*notifyField{
+	&notifyField{ variable: "%(domain)",     sdName: "domain",     journalName: "PDNS_DOMAIN" },
+	&notifyField{ variable: "%(groupName)",  sdName: "groupName",  journalName: "PDNS_GROUP_NAME" },
+	&notifyField{ variable: "%(name)",       sdName: "name",       journalName: "PDNS_NAME" },
+	&notifyField{ variable: "%(type)",       sdName: "type",       journalName: "PDNS_TYPE" },
+	&notifyField{ variable: "%(content)",    sdName: "content",    journalName: "PDNS_CONTENT" },
+	&notifyField{ variable: "%(oldAlive)",   sdName: "oldAlive",   journalName: "PDNS_OLD_ALIVE" },
+	&notifyField{ variable: "%(newAlive)",   sdName: "newAlive",   journalName: "PDNS_NEW_ALIVE" },
+	&notifyField{ variable: "%(flapping)",   sdName: "flapping",   journalName: "PDNS_FLAPPING" },
+	&notifyField{ variable: "%(targetName)", sdName: "targetName", journalName: "PDNS_TARGET_NAME" },
+	&notifyField{ variable: "%(dest)",       sdName: "dest",       journalName: "PDNS_DEST" },
+	&notifyField{ variable: "%(alive)",      sdName: "alive",      journalName: "PDNS_ALIVE" },
+}
+
+// notifySeverity is warning when down, otherwise notice
+func notifySeverity(varMap map[string]string) (int) {
+	alive, ok := varMap["%(newAlive)"]
+	if !ok {
+		alive, ok = varMap["%(alive)"]
+	}
+	if ok && alive == "false" {
+		return helper.SyslogSeverityWarning
+	}
+	return helper.SyslogSeverityNotice
+}
+
+func syslogHeaderValue(value string, maxLen int) (string) {
+	if value == "" {
+		return "-"
+	}
+	// header fields are printable ascii without space
+	value = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+	return value
+}
+
+func syslogParamValue(value string) (string) {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "]", "\\]").Replace(value)
+}
+
+// formatSyslogMessage is format message of rfc5424
+func formatSyslogMessage(syslogContext *contexter.Syslog, hostname string, pid int, varList []string, subject string, now time.Time) (string) {
+	varMap := newVarMap(varList)
+	facilityName := syslogContext.Facility
+	if facilityName == "" {
+		facilityName = defaultSyslogFacility
+	}
+	facility := helper.SyslogFacilityMap[strings.ToLower(facilityName)]
+	appName := syslogContext.AppName
+	if appName == "" {
+		appName = defaultSyslogAppName
+	}
+	msgID := "-"
+	if _, ok := varMap["%(newAlive)"]; ok {
+		msgID = "alive"
+	}
+	sdID := syslogContext.StructuredDataID
+	if sdID == "" {
+		sdID = defaultSyslogStructuredDataID
+	}
+	paramList := make([]string, 0, len(notifyFieldList))
+	for _, field := range notifyFieldList {
+		if value, ok := varMap[field.variable]; ok {
+			paramList = append(paramList, fmt.Sprintf("%v=\"%v\"", field.sdName, syslogParamValue(value)))
+		}
+	}
+	structuredData := "-"
+	if len(paramList) > 0 {
+		structuredData = "[" + syslogHeaderValue(sdID, 32) + " " + strings.Join(paramList, " ") + "]"
+	}
+	return fmt.Sprintf("<%v>1 %v %v %v %v %v %v %v",
+		facility * 8 + notifySeverity(varMap),
+		now.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderValue(hostname, 255),
+		syslogHeaderValue(appName, 48),
+		pid,
+		msgID,
+		structuredData,
+		strings.NewReplacer(varList...).Replace(subject))
+}
+
+func (n *Notifier) dialSyslog(syslogContext *contexter.Syslog) (net.Conn, error) {
+	timeout := time.Duration(syslogContext.Timeout) * time.Second
+	switch strings.ToUpper(syslogContext.Network) {
+	case "UDP":
+		return net.DialTimeout("udp", syslogContext.Address, timeout)
+	case "TCP":
+		return net.DialTimeout("tcp", syslogContext.Address, timeout)
+	case "TLS":
+		tlsConfig, err := helper.NewTLSConfig(syslogContext.Address, &helper.HTTPClientOption{
+			TLSSkipVerify:     syslogContext.TLSSkipVerify,
+			TLSServerName:     syslogContext.TLSServerName,
+			TLSCAFile:         syslogContext.TLSCAFile,
+			TLSClientCertFile: syslogContext.TLSClientCertFile,
+			TLSClientKeyFile:  syslogContext.TLSClientKeyFile,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return tls.DialWithDialer(&net.Dialer{ Timeout: timeout }, "tcp", syslogContext.Address, tlsConfig)
+	case "UNIX":
+		address := syslogContext.Address
+		if address == "" {
+			address = defaultSyslogUnixAddress
+		}
+		return net.DialTimeout("unixgram", address, timeout)
+	default:
+		return nil, errors.Errorf("unexpected network (%v)", syslogContext.Network)
+	}
+}
+
+func (n *Notifier) sendSyslog(syslogContext *contexter.Syslog, varList []string, subject string) (error) {
+	message := formatSyslogMessage(syslogContext, n.hostname, os.Getpid(), varList, subject, time.Now())
+	conn, err := n.dialSyslog(syslogContext)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("can not connect syslog (%v) (%v)", syslogContext.Network, syslogContext.Address))
+	}
+	defer conn.Close()
+	if syslogContext.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(time.Duration(syslogContext.Timeout) * time.Second))
+	}
+	switch strings.ToUpper(syslogContext.Network) {
+	case "TCP", "TLS":
+		// octet counting framing (rfc5425, rfc6587)
+		message = fmt.Sprintf("%v %v", len(message), message)
+	}
+	if _, err := conn.Write([]byte(message)); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("can not write syslog message (%v) (%v)", syslogContext.Network, syslogContext.Address))
+	}
+	return nil
+}
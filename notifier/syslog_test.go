@@ -0,0 +1,160 @@
+package notifier
+
+import (
+	"github.com/potix/pdns-record-updater/contexter"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatSyslogMessage(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	testCaseList := []struct {
+		name          string
+		syslogContext *contexter.Syslog
+		hostname      string
+		varList       []string
+		subject       string
+		expected      string
+	}{
+		{
+			name:          "default",
+			syslogContext: &contexter.Syslog{},
+			hostname:      "watcher1",
+			varList:       []string{ "%(name)", "www", "%(newAlive)", "false" },
+			subject:       "%(name) down",
+			expected:      "<28>1 2020-01-02T03:04:05.000006Z watcher1 pdns-record-updater 123 alive [pdns@32473 name=\"www\" newAlive=\"false\"] www down",
+		},
+		{
+			name:          "no structured data",
+			syslogContext: &contexter.Syslog{ Facility: "local0", AppName: "app" },
+			hostname:      "watcher1",
+			varList:       []string{ "%(hostname)", "watcher1" },
+			subject:       "%(hostname) started",
+			expected:      "<133>1 2020-01-02T03:04:05.000006Z watcher1 app 123 - - watcher1 started",
+		},
+		{
+			name:          "param value escaping",
+			syslogContext: &contexter.Syslog{ StructuredDataID: "test@32473" },
+			hostname:      "watcher1",
+			varList:       []string{ "%(content)", "a\"b\\c]d", "%(alive)", "true" },
+			subject:       "subject",
+			expected:      "<29>1 2020-01-02T03:04:05.000006Z watcher1 pdns-record-updater 123 - [test@32473 content=\"a\\\"b\\\\c\\]d\" alive=\"true\"] subject",
+		},
+		{
+			name:          "header truncation",
+			syslogContext: &contexter.Syslog{ AppName: strings.Repeat("a", 60), StructuredDataID: strings.Repeat("s", 30) + "@32473" },
+			hostname:      "host name\n" + strings.Repeat("h", 300),
+			varList:       []string{ "%(name)", "www" },
+			subject:       "subject",
+			expected:      "<29>1 2020-01-02T03:04:05.000006Z hostname" + strings.Repeat("h", 247) + " " + strings.Repeat("a", 48) + " 123 - [" + strings.Repeat("s", 30) + "@3 name=\"www\"] subject",
+		},
+		{
+			name:          "empty header",
+			syslogContext: &contexter.Syslog{},
+			hostname:      "",
+			varList:       nil,
+			subject:       "subject",
+			expected:      "<29>1 2020-01-02T03:04:05.000006Z - pdns-record-updater 123 - - subject",
+		},
+	}
+	for _, testCase := range testCaseList {
+		message := formatSyslogMessage(testCase.syslogContext, testCase.hostname, 123, testCase.varList, testCase.subject, now)
+		if message != testCase.expected {
+			t.Errorf("%v:\n got  %q\n want %q", testCase.name, message, testCase.expected)
+		}
+	}
+}
+
+// newTestSyslogListener is listen tcp or tls on loopback, certificate of httptest is borrowed for tls
+func newTestSyslogListener(t *testing.T, network string) (net.Listener) {
+	if network != "tls" {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("can not listen: %v", err)
+		}
+		return listener
+	}
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	certificateList := server.TLS.Certificates
+	server.Close()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{ Certificates: certificateList })
+	if err != nil {
+		t.Fatalf("can not listen: %v", err)
+	}
+	return listener
+}
+
+func TestSendSyslogOctetCounting(t *testing.T) {
+	for _, network := range []string{ "tcp", "tls" } {
+		listener := newTestSyslogListener(t, network)
+		receivedChan := make(chan string, 1)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				receivedChan <- ""
+				return
+			}
+			defer conn.Close()
+			received, _ := ioutil.ReadAll(conn)
+			receivedChan <- string(received)
+		}()
+		syslogContext := &contexter.Syslog{
+			Network:       network,
+			Address:       listener.Addr().String(),
+			TLSSkipVerify: true,
+			Timeout:       5,
+		}
+		n := &Notifier{ hostname: "watcher1" }
+		if err := n.sendSyslog(syslogContext, []string{ "%(name)", "www" }, "%(name) down\nsecond line"); err != nil {
+			t.Fatalf("%v: can not send syslog: %v", network, err)
+		}
+		received := <-receivedChan
+		listener.Close()
+		frameList := strings.SplitN(received, " ", 2)
+		if len(frameList) != 2 {
+			t.Fatalf("%v: no octet count: %q", network, received)
+		}
+		length, err := strconv.Atoi(frameList[0])
+		if err != nil || length != len(frameList[1]) {
+			t.Errorf("%v: octet count = %v, message length = %v", network, frameList[0], len(frameList[1]))
+		}
+		if !strings.HasPrefix(frameList[1], "<29>1 ") || !strings.HasSuffix(frameList[1], "www down\nsecond line") {
+			t.Errorf("%v: message = %q", network, frameList[1])
+		}
+	}
+}
+
+func TestSendSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can not listen: %v", err)
+	}
+	defer conn.Close()
+	syslogContext := &contexter.Syslog{ Network: "udp", Address: conn.LocalAddr().String(), Timeout: 5 }
+	if err := (&Notifier{ hostname: "watcher1" }).sendSyslog(syslogContext, nil, "subject"); err != nil {
+		t.Fatalf("can not send syslog: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	buffer := make([]byte, 2048)
+	size, _, err := conn.ReadFrom(buffer)
+	if err != nil {
+		t.Fatalf("can not receive: %v", err)
+	}
+	// datagram is not framed
+	if message := string(buffer[:size]); !strings.HasPrefix(message, "<29>1 ") {
+		t.Errorf("message = %q", message)
+	}
+}
+
+func TestDialSyslogUnexpectedNetwork(t *testing.T) {
+	if _, err := new(Notifier).dialSyslog(&contexter.Syslog{ Network: "sctp", Address: "127.0.0.1:514" }); err == nil {
+		t.Errorf("no error with unexpected network")
+	}
+}
@@ -0,0 +1,123 @@
+package notifier
+
+import (
+	"github.com/pkg/errors"
+	"github.com/potix/belog"
+	"github.com/potix/pdns-record-updater/contexter"
+	"github.com/potix/pdns-record-updater/helper"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"io/ioutil"
+	"strings"
+	"time"
+	"fmt"
+)
+
+const defaultWebhookBody = "{\"subject\":\"%(subject)\",\"body\":\"%(body)\"}"
+
+func jsonEscape(value string) (string) {
+	escaped, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	// strip quotes
+	return string(escaped[1:len(escaped) - 1])
+}
+
+// newWebhookReplacer is create replacer of webhook body, values are escaped when body is json
+func newWebhookReplacer(varList []string, subject string, body string, escape bool) (*strings.Replacer) {
+	replacer := strings.NewReplacer(varList...)
+	webhookVarList := make([]string, 0, len(varList) + 4)
+	webhookVarList = append(webhookVarList, varList...)
+	webhookVarList = append(webhookVarList, "%(subject)", replacer.Replace(subject), "%(body)", replacer.Replace(body))
+	if escape {
+		for i := 1; i < len(webhookVarList); i += 2 {
+			webhookVarList[i] = jsonEscape(webhookVarList[i])
+		}
+	}
+	return strings.NewReplacer(webhookVarList...)
+}
+
+func (n *Notifier) sendWebhookOnce(webhookContext *contexter.Webhook, httpClient *http.Client, method string, contentType string, body string) (error) {
+	request, err := http.NewRequest(method, webhookContext.URL, strings.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("can not create request (%v)", webhookContext.URL))
+	}
+	for k, v := range webhookContext.HeaderMap {
+		if strings.ToUpper(k) == "HOST" {
+			request.Host = v
+		} else {
+			request.Header.Set(k, v)
+		}
+	}
+	request.Header.Set("Content-Type", contentType)
+	res, err := httpClient.Do(request)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("can not send request (%v)", webhookContext.URL))
+	}
+	defer res.Body.Close()
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("can not read response (%v)", webhookContext.URL))
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Errorf("unexpected status code (%v) (%v) (%v)", webhookContext.URL, res.StatusCode, string(resBody))
+	}
+	belog.Debug("webhook ok (%v)", webhookContext.URL)
+	return nil
+}
+
+// postWebhook is send body to webhook with retry, method and content type are taken from webhook context
+func (n *Notifier) postWebhook(webhookContext *contexter.Webhook, body string) (error) {
+	u, err := url.Parse(webhookContext.URL)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("can not parse url (%v)", webhookContext.URL))
+	}
+	httpClient, err := helper.NewHTTPClient(u.Scheme, u.Host, &helper.HTTPClientOption{
+		TLSSkipVerify:     webhookContext.TLSSkipVerify,
+		TLSServerName:     webhookContext.TLSServerName,
+		TLSCAFile:         webhookContext.TLSCAFile,
+		TLSClientCertFile: webhookContext.TLSClientCertFile,
+		TLSClientKeyFile:  webhookContext.TLSClientKeyFile,
+		Timeout:           webhookContext.Timeout,
+	})
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("can not create http client (%v)", webhookContext.URL))
+	}
+	method := webhookContext.Method
+	if method == "" {
+		method = "POST"
+	}
+	var i uint32
+	for i = 0; i <= webhookContext.Retry; i++ {
+		err = n.sendWebhookOnce(webhookContext, httpClient, strings.ToUpper(method), webhookContentType(webhookContext), body)
+		if err == nil {
+			return nil
+		}
+		belog.Error("retry webhook (%v)", err)
+		if webhookContext.RetryWait > 0 && i < webhookContext.Retry {
+			time.Sleep(time.Duration(webhookContext.RetryWait) * time.Second)
+		}
+	}
+	return errors.Wrap(err, fmt.Sprintf("give up retry (%v)", webhookContext.URL))
+}
+
+func webhookContentType(webhookContext *contexter.Webhook) (string) {
+	for k, v := range webhookContext.HeaderMap {
+		if strings.ToUpper(k) == "CONTENT-TYPE" {
+			return v
+		}
+	}
+	return "application/json"
+}
+
+func (n *Notifier) sendWebhook(webhookContext *contexter.Webhook, varList []string, subject string, body string) (error) {
+	webhookBody := webhookContext.Body
+	if webhookBody == "" {
+		webhookBody = defaultWebhookBody
+	}
+	contentType := webhookContentType(webhookContext)
+	replacer := newWebhookReplacer(varList, subject, body, strings.Contains(strings.ToLower(contentType), "json"))
+	return n.postWebhook(webhookContext, replacer.Replace(webhookBody))
+}
@@ -0,0 +1,99 @@
+package notifier
+
+import (
+	"github.com/potix/pdns-record-updater/contexter"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSendWebhook(t *testing.T) {
+	var method, host, token, contentType string
+	var requestBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		host = r.Host
+		token = r.Header.Get("X-Token")
+		contentType = r.Header.Get("Content-Type")
+		requestBody, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+	webhookContext := &contexter.Webhook{
+		URL:       server.URL,
+		Method:    "put",
+		HeaderMap: map[string]string{ "X-Token": "secret", "Host": "hook.example.com" },
+		Body:      "{\"text\":\"%(subject)\",\"detail\":\"%(detail)\"}",
+		Timeout:   5,
+	}
+	detail := "line1\nline2 \"quoted\" \\ end"
+	varList := []string{ "%(name)", "www", "%(detail)", detail }
+	if err := new(Notifier).sendWebhook(webhookContext, varList, "%(name) down", "body"); err != nil {
+		t.Fatalf("can not send webhook: %v", err)
+	}
+	if method != "PUT" {
+		t.Errorf("method = %v, want PUT", method)
+	}
+	if host != "hook.example.com" {
+		t.Errorf("host = %v, want hook.example.com", host)
+	}
+	if token != "secret" {
+		t.Errorf("X-Token = %v, want secret", token)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %v, want application/json", contentType)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(requestBody, &payload); err != nil {
+		t.Fatalf("body is not json (%v): %v", string(requestBody), err)
+	}
+	if payload["text"] != "www down" {
+		t.Errorf("text = %v, want www down", payload["text"])
+	}
+	if payload["detail"] != detail {
+		t.Errorf("detail = %q, want %q", payload["detail"], detail)
+	}
+}
+
+func TestSendWebhookRetry(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	webhookContext := &contexter.Webhook{
+		URL:     server.URL,
+		Timeout: 5,
+		Retry:   2,
+	}
+	if err := new(Notifier).sendWebhook(webhookContext, nil, "subject", "body"); err != nil {
+		t.Fatalf("can not send webhook: %v", err)
+	}
+	if count := atomic.LoadInt32(&count); count != 3 {
+		t.Errorf("request count = %v, want 3", count)
+	}
+}
+
+func TestSendWebhookGiveUp(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	webhookContext := &contexter.Webhook{
+		URL:     server.URL,
+		Timeout: 5,
+		Retry:   1,
+	}
+	if err := new(Notifier).sendWebhook(webhookContext, nil, "subject", "body"); err == nil {
+		t.Fatalf("no error after retry is exceeded")
+	}
+	if count := atomic.LoadInt32(&count); count != 2 {
+		t.Errorf("request count = %v, want 2", count)
+	}
+}
@@ -0,0 +1,119 @@
+package persister
+
+import (
+	"github.com/pkg/errors"
+	"github.com/potix/belog"
+	"github.com/potix/pdns-record-updater/contexter"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+	"fmt"
+	"os"
+)
+
+// staleIntervalCount is number of watch intervals, state of target older than this is not restored
+const staleIntervalCount = 3
+
+// State is runtime state of watcher
+type State struct {
+	SavedAt          time.Time                                `json:"savedAt"`
+	TargetMap        map[string]*contexter.TargetState        `json:"targetMap"`
+	DynamicRecordMap map[string]*contexter.DynamicRecordState `json:"dynamicRecordMap"`
+}
+
+// NewState is create state
+func NewState() (*State) {
+	return &State{
+		TargetMap:        make(map[string]*contexter.TargetState),
+		DynamicRecordMap: make(map[string]*contexter.DynamicRecordState),
+	}
+}
+
+// GetFreshTargetState is get state of target, return nil when it does not exist or is older than a few watch intervals
+func (s *State) GetFreshTargetState(targetName string, watchInterval time.Duration, now time.Time) (*contexter.TargetState) {
+	targetState, ok := s.TargetMap[targetName]
+	if !ok {
+		return nil
+	}
+	if now.Sub(targetState.LastProbeTime) > watchInterval * staleIntervalCount {
+		belog.Info("state of target (%v) is stale (last probe at %v)", targetName, targetState.LastProbeTime)
+		return nil
+	}
+	return targetState
+}
+
+// DynamicRecordKey is key of dynamic record in state
+func DynamicRecordKey(domain string, groupName string, record *contexter.DynamicRecord) (string) {
+	return fmt.Sprintf("%v %v %v %v %v", domain, groupName, record.Name, record.Type, record.Content)
+}
+
+// Persister is state store backed by local file
+type Persister struct {
+	path  string
+	mutex *sync.Mutex
+}
+
+// Load is load state, return nil when state file does not exist
+func (p *Persister) Load() (*State, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	data, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			belog.Info("no state file (%v)", p.path)
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, fmt.Sprintf("can not read state file (%v)", p.path))
+	}
+	state := NewState()
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("can not unmarshal state file (%v)", p.path))
+	}
+	return state, nil
+}
+
+// Save is save state
+func (p *Persister) Save(state *State) (error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	state.SavedAt = time.Now()
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "can not marshal state")
+	}
+	// write to temporary file and rename it, in order not to break state file on crash
+	tmpFile, err := ioutil.TempFile(filepath.Dir(p.path), filepath.Base(p.path) + ".")
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("can not create temporary state file (%v)", p.path))
+	}
+	tmpPath := tmpFile.Name()
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return errors.Wrap(err, fmt.Sprintf("can not write temporary state file (%v)", tmpPath))
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return errors.Wrap(err, fmt.Sprintf("can not sync temporary state file (%v)", tmpPath))
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return errors.Wrap(err, fmt.Sprintf("can not close temporary state file (%v)", tmpPath))
+	}
+	if err := os.Rename(tmpPath, p.path); err != nil {
+		os.Remove(tmpPath)
+		return errors.Wrap(err, fmt.Sprintf("can not rename state file (%v -> %v)", tmpPath, p.path))
+	}
+	return nil
+}
+
+// New is create persister
+func New(path string) (*Persister) {
+	return &Persister{
+		path:  path,
+		mutex: new(sync.Mutex),
+	}
+}
@@ -0,0 +1,201 @@
+package updater
+
+import (
+	"github.com/pkg/errors"
+	"github.com/potix/belog"
+	"github.com/potix/pdns-record-updater/contexter"
+	"github.com/potix/pdns-record-updater/api/structure"
+	"github.com/potix/pdns-record-updater/helper"
+	"strings"
+)
+
+type recordKey struct {
+	domain     string
+	groupName  string
+	name       string
+	recordType string
+	content    string
+}
+
+func newRecordKey(domain string, record *structure.DynamicRecordWatchResultResponse) (recordKey) {
+	return recordKey{
+		domain:     domain,
+		groupName:  record.GroupName,
+		name:       record.Name,
+		recordType: strings.ToUpper(record.Type),
+		content:    record.Content,
+	}
+}
+
+// consensusThreshold is number of watchers that must regard record as alive
+func consensusThreshold(updaterContext *contexter.Updater, watcherCount int) (int) {
+	switch strings.ToUpper(updaterContext.ConsensusMode) {
+	case "ANY":
+		return 1
+	case "ALL":
+		return watcherCount
+	case "NOFM":
+		return int(updaterContext.ConsensusCount)
+	default:
+		// majority
+		return watcherCount / 2 + 1
+	}
+}
+
+func isConsensusMode(updaterContext *contexter.Updater) (bool) {
+	consensusMode := strings.ToUpper(updaterContext.ConsensusMode)
+	return consensusMode != "" && consensusMode != "FAILOVER"
+}
+
+type pdnsRecordKey struct {
+	name       string
+	recordType string
+	content    string
+}
+
+// getPdnsAliveMap is get alive of dynamic records in zone of pdns, disabled record is regarded as down
+func (u *Updater) getPdnsAliveMap(updaterContext *contexter.Updater, domain string) (map[pdnsRecordKey]bool, error) {
+	rrsetList, err := u.getZoneRrsetList(updaterContext, domain)
+	if err != nil {
+		return nil, err
+	}
+	pdnsAliveMap := make(map[pdnsRecordKey]bool)
+	for _, rrset := range rrsetList {
+		for _, record := range rrset.RecordList {
+			pdnsAliveMap[pdnsRecordKey{ name: rrset.Name, recordType: strings.ToUpper(rrset.Type), content: record.Content }] = !record.Disabled
+		}
+	}
+	return pdnsAliveMap, nil
+}
+
+func newPdnsRecordKey(domain string, record *structure.DynamicRecordWatchResultResponse) (pdnsRecordKey) {
+	return pdnsRecordKey{
+		name:       helper.FixupRrsetName(record.Name, domain, record.Type, true),
+		recordType: strings.ToUpper(record.Type),
+		content:    helper.FixupRrsetContent(record.Content, domain, record.Type, true),
+	}
+}
+
+// mergeWatchResult is decide alive of dynamic records by quorum of watchers.
+// records are union of records of all watchers that responded.
+// alive when alive votes reach threshold, down when alive votes can not reach threshold even if watchers that did not respond vote alive.
+// otherwise, previous decision is used, and current state in pdns is kept when there is no previous decision.
+func (u *Updater) mergeWatchResult(updaterContext *contexter.Updater, apiServerURLList []contexter.APIServerURL, watchResultResponseMap map[string]*structure.WatchResultResponse) (*structure.WatchResultResponse, error) {
+	watcherCount := len(apiServerURLList)
+	threshold := consensusThreshold(updaterContext, watcherCount)
+	// use result of first api server that responded as base of zone, and collect dynamic records from all api servers
+	var baseWatchResultResponse *structure.WatchResultResponse
+	baseZoneMap := make(map[string]*structure.ZoneWatchResultResponse)
+	recordListMap := make(map[string]structure.DynamicRecordListWatchResultResponse)
+	aliveVoteMap := make(map[recordKey]int)
+	downVoteMap := make(map[recordKey]int)
+	for _, apiServerURL := range apiServerURLList {
+		watchResultResponse, ok := watchResultResponseMap[apiServerURL.String()]
+		if !ok {
+			continue
+		}
+		if baseWatchResultResponse == nil {
+			baseWatchResultResponse = watchResultResponse
+		}
+		for domain, zoneWatchResultResponse := range watchResultResponse.ZoneMap {
+			if _, ok := baseZoneMap[domain]; !ok {
+				baseZoneMap[domain] = zoneWatchResultResponse
+			}
+			for _, record := range zoneWatchResultResponse.DynamicRecordList {
+				key := newRecordKey(domain, record)
+				if aliveVoteMap[key] == 0 && downVoteMap[key] == 0 {
+					recordListMap[domain] = append(recordListMap[domain], record)
+				}
+				if record.Alive {
+					aliveVoteMap[key]++
+				} else {
+					downVoteMap[key]++
+				}
+			}
+		}
+	}
+	if baseWatchResultResponse == nil {
+		return nil, errors.Errorf("no watcher result")
+	}
+	for key := range u.lastAliveMap {
+		if aliveVoteMap[key] == 0 && downVoteMap[key] == 0 {
+			// record disappeared from all watchers
+			delete(u.lastAliveMap, key)
+		}
+	}
+	mergedWatchResultResponse := &structure.WatchResultResponse{
+		ZoneMap:        make(map[string]*structure.ZoneWatchResultResponse),
+		TargetMap:      baseWatchResultResponse.TargetMap,
+		MaintenanceMap: baseWatchResultResponse.MaintenanceMap,
+	}
+	for domain, zoneWatchResultResponse := range baseZoneMap {
+		recordList := recordListMap[domain]
+		newZoneWatchResultResponse := &structure.ZoneWatchResultResponse{
+			PrimaryNameServer: zoneWatchResultResponse.PrimaryNameServer,
+			Email:             zoneWatchResultResponse.Email,
+			NameServerList:    zoneWatchResultResponse.NameServerList,
+			StaticRecordList:  zoneWatchResultResponse.StaticRecordList,
+			DynamicRecordList: make(structure.DynamicRecordListWatchResultResponse, 0, len(recordList)),
+		}
+		var pdnsAliveMap map[pdnsRecordKey]bool
+		decidedAliveMap := make(map[recordKey]bool)
+		skip := false
+		for _, record := range recordList {
+			key := newRecordKey(domain, record)
+			aliveVote := aliveVoteMap[key]
+			downVote := downVoteMap[key]
+			newRecord := *record
+			if aliveVote >= threshold {
+				newRecord.Alive = true
+			} else if downVote > watcherCount - threshold {
+				newRecord.Alive = false
+			} else if lastAlive, ok := u.lastAliveMap[key]; ok {
+				belog.Notice("no quorum of %v %v %v %v %v (alive %v, down %v, threshold %v/%v), keep alive = %v",
+					domain, record.GroupName, record.Name, record.Type, record.Content, aliveVote, downVote, threshold, watcherCount, lastAlive)
+				newRecord.Alive = lastAlive
+			} else {
+				if pdnsAliveMap == nil {
+					var err error
+					if pdnsAliveMap, err = u.getPdnsAliveMap(updaterContext, domain); err != nil {
+						belog.Warn("skip zone (%v), because alive of some records can not be decided and current state can not be got (%v)", domain, err)
+						skip = true
+						break
+					}
+				}
+				pdnsAlive, ok := pdnsAliveMap[newPdnsRecordKey(domain, record)]
+				if !ok {
+					belog.Warn("no quorum of %v %v %v %v %v (alive %v, down %v, threshold %v/%v) and not in pdns, leave it out",
+						domain, record.GroupName, record.Name, record.Type, record.Content, aliveVote, downVote, threshold, watcherCount)
+					continue
+				}
+				belog.Warn("no quorum of %v %v %v %v %v (alive %v, down %v, threshold %v/%v) and no previous decision, keep alive in pdns = %v",
+					domain, record.GroupName, record.Name, record.Type, record.Content, aliveVote, downVote, threshold, watcherCount, pdnsAlive)
+				// state in pdns is not a decision, so it is not recorded as previous decision
+				newRecord.Alive = pdnsAlive
+				newZoneWatchResultResponse.DynamicRecordList = append(newZoneWatchResultResponse.DynamicRecordList, &newRecord)
+				continue
+			}
+			decidedAliveMap[key] = newRecord.Alive
+			newZoneWatchResultResponse.DynamicRecordList = append(newZoneWatchResultResponse.DynamicRecordList, &newRecord)
+		}
+		if skip {
+			continue
+		}
+		for key, alive := range decidedAliveMap {
+			u.lastAliveMap[key] = alive
+		}
+		mergedWatchResultResponse.ZoneMap[domain] = newZoneWatchResultResponse
+	}
+	return mergedWatchResultResponse, nil
+}
+
+func (u *Updater) getWatchResult(updaterContext *contexter.Updater) (*structure.WatchResultResponse, error) {
+	if !isConsensusMode(updaterContext) {
+		return u.client.GetWatchResult()
+	}
+	watchResultResponseMap, err := u.client.GetWatchResultMap()
+	if err != nil {
+		return nil, err
+	}
+	return u.mergeWatchResult(updaterContext, u.context.GetAPIClient().APIServerURLList, watchResultResponseMap)
+}
@@ -7,10 +7,12 @@ import (
         "github.com/potix/pdns-record-updater/api/client"
 	"github.com/potix/pdns-record-updater/api/structure"
 	"github.com/potix/pdns-record-updater/helper"
+	"github.com/potix/pdns-record-updater/metrics"
         "sync/atomic"
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"net"
 	"io/ioutil"
 	"bytes"
 	"strings"
@@ -24,6 +26,8 @@ type Updater struct {
 	client         *client.Client
 	context        *contexter.Context
 	running        uint32
+	metricsServer  *http.Server
+	lastAliveMap   map[recordKey]bool
 }
 
 type recordData struct {
@@ -193,35 +197,42 @@ func (u *Updater) zoneWatcherResultResponseToRrset(updaterContext *contexter.Upd
 	return rrsets
 }
 
-func (u *Updater) get(updaterContext *contexter.Updater, resource string) (int, error) {
+func (u *Updater) get(updaterContext *contexter.Updater, resource string) (int, []byte, error) {
         parsedURL, err := url.Parse(resource)
         if err != nil {
-                return 0, errors.Errorf("can not parse url (%v)", resource)
+                return 0, nil, errors.Errorf("can not parse url (%v)", resource)
+        }
+        httpClient, err := helper.NewHTTPClient(parsedURL.Scheme, parsedURL.Host, &helper.HTTPClientOption{ Timeout: 30 })
+        if err != nil {
+                return 0, nil, errors.Wrap(err, fmt.Sprintf("can not create http client (%v)", resource))
         }
-        httpClient := helper.NewHTTPClient(parsedURL.Scheme, parsedURL.Host, false, 30)
         request, err := http.NewRequest("GET", resource, nil)
         if err != nil {
-                return 0, errors.Wrap(err, fmt.Sprintf("can not create request (%v)", resource))
+                return 0, nil, errors.Wrap(err, fmt.Sprintf("can not create request (%v)", resource))
         }
 	request.Header.Set("Accept", "*/*")
 	request.Header.Set("X-API-Key", updaterContext.PdnsAPIKey)
+	start := time.Now()
         res, err := httpClient.Do(request)
         if err != nil {
-                return 0, errors.Wrap(err, fmt.Sprintf("can not request (%v)", resource))
+		metrics.ObservePdnsRequest(request.Method, 0, time.Since(start))
+                return 0, nil, errors.Wrap(err, fmt.Sprintf("can not request (%v)", resource))
         }
         defer res.Body.Close()
+	metrics.ObservePdnsRequest(request.Method, res.StatusCode, time.Since(start))
         if res.StatusCode != 200 && res.StatusCode != 204 {
-                return res.StatusCode, errors.Errorf("unexpected status code (%v) (%v)", resource, res.StatusCode)
+                return res.StatusCode, nil, errors.Errorf("unexpected status code (%v) (%v)", resource, res.StatusCode)
         }
+	var body []byte
 	if res.StatusCode == 200 {
-		body, err := ioutil.ReadAll(res.Body)
+		body, err = ioutil.ReadAll(res.Body)
 		if err != nil {
-			return res.StatusCode, errors.Wrap(err, fmt.Sprintf("can not read body (%v)", resource))
+			return res.StatusCode, nil, errors.Wrap(err, fmt.Sprintf("can not read body (%v)", resource))
 		}
 		belog.Debug("body: %v", string(body))
 	}
         belog.Debug("http ok (%v)", resource)
-        return res.StatusCode, nil
+        return res.StatusCode, body, nil
 }
 
 func (u *Updater) postPutPatch(updaterContext *contexter.Updater, resource string, method string, data interface{}) (error) {
@@ -229,7 +240,10 @@ func (u *Updater) postPutPatch(updaterContext *contexter.Updater, resource strin
         if err != nil {
                 return errors.Errorf("can not parse url (%v)", resource)
         }
-        httpClient := helper.NewHTTPClient(parsedURL.Scheme, parsedURL.Host, false, 30)
+        httpClient, err := helper.NewHTTPClient(parsedURL.Scheme, parsedURL.Host, &helper.HTTPClientOption{ Timeout: 30 })
+        if err != nil {
+                return errors.Wrap(err, fmt.Sprintf("can not create http client (%v)", resource))
+        }
         jsonData, err := json.Marshal(data)
         if err != nil {
                 return errors.Wrap(err, fmt.Sprintf("can not marsnale request data (%v)", resource))
@@ -242,11 +256,14 @@ func (u *Updater) postPutPatch(updaterContext *contexter.Updater, resource strin
 	request.Header.Set("Accept", "*/*")
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Set("X-API-Key", updaterContext.PdnsAPIKey)
+	start := time.Now()
         res, err := httpClient.Do(request)
         if err != nil {
+		metrics.ObservePdnsRequest(request.Method, 0, time.Since(start))
                 return errors.Wrap(err, fmt.Sprintf("can not request (%v)", resource))
         }
         defer res.Body.Close()
+	metrics.ObservePdnsRequest(request.Method, res.StatusCode, time.Since(start))
         if res.StatusCode != 200 && res.StatusCode != 201 && res.StatusCode != 204 {
                 return errors.Errorf("unexpected status code (%v) (%v)", resource, res.StatusCode)
         }
@@ -280,7 +297,7 @@ func (u *Updater) createZone(updaterContext *contexter.Updater, domain string, z
 
 func (u *Updater) getZone(updaterContext *contexter.Updater, domain string) (bool, error) {
 	resource := fmt.Sprintf("%v/api/v1/servers/localhost/zones/%v", updaterContext.PdnsServer, helper.NoDotDomain(domain))
-	statusCode, err := u.get(updaterContext, resource)
+	statusCode, _, err := u.get(updaterContext, resource)
 	if err != nil {
 		if statusCode == 0 || statusCode == 401 {
 			return false, errors.Wrap(err, fmt.Sprintf("can not get zone (%v)", resource))
@@ -295,18 +312,39 @@ func (u *Updater) getZone(updaterContext *contexter.Updater, domain string) (boo
 	return true, nil
 }
 
+// getZoneRrsetList is get current rrsets of zone in pdns
+func (u *Updater) getZoneRrsetList(updaterContext *contexter.Updater, domain string) ([]*rrsetData, error) {
+	resource := fmt.Sprintf("%v/api/v1/servers/localhost/zones/%v", updaterContext.PdnsServer, helper.NoDotDomain(domain))
+	statusCode, body, err := u.get(updaterContext, resource)
+	if err != nil {
+		if statusCode == 404 || statusCode == 422 {
+			// zone does not exist yet
+			return make([]*rrsetData, 0), nil
+		}
+		return nil, errors.Wrap(err, fmt.Sprintf("can not get zone (%v)", resource))
+	}
+	zoneResponse := new(zoneRequest)
+	if len(body) == 0 {
+		return make([]*rrsetData, 0), nil
+	}
+	if err := json.Unmarshal(body, zoneResponse); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("can not unmarshal zone (%v)", resource))
+	}
+	return zoneResponse.RrsetList, nil
+}
+
 func (u *Updater) updateLoop() () {
 	for atomic.LoadUint32(&u.running) == 1 {
+		updaterContext := u.context.GetUpdater()
 		var watchResultResponse *structure.WatchResultResponse
 		var err error
 		for {
-			if watchResultResponse, err = u.client.GetWatchResult(); err != nil {
+			if watchResultResponse, err = u.getWatchResult(updaterContext); err != nil {
 				belog.Error("can not get watcher result (%v)", err)
 				continue;
 			}
 			break
 		}
-		updaterContext := u.context.GetUpdater()
 		for domain, zoneWatchResultResponse := range watchResultResponse.ZoneMap {
 			exist, err :=  u.getZone(updaterContext, domain)
 			if err != nil {
@@ -329,15 +367,46 @@ func (u *Updater) updateLoop() () {
 	}
 }
 
+func (u *Updater) startMetricsServer(addrPort string) (error) {
+	listener, err := net.Listen("tcp", addrPort)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("can not listen metrics server (%v)", addrPort))
+	}
+	serveMux := http.NewServeMux()
+	serveMux.Handle("/metrics", metrics.Handler())
+	u.metricsServer = &http.Server{
+		Addr:           addrPort,
+		Handler:        serveMux,
+		ReadTimeout:    30 * time.Second,
+		WriteTimeout:   30 * time.Second,
+		MaxHeaderBytes: 1 << 20,
+	}
+	go func() {
+		if err := u.metricsServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not serve metrics (%v)", addrPort)))
+		}
+	}()
+	return nil
+}
+
 // Start is start
-func (u Updater) Start() {
+func (u *Updater) Start() (error) {
+	if addrPort := u.context.GetUpdater().MetricsAddrPort; addrPort != "" {
+		if err := u.startMetricsServer(addrPort); err != nil {
+			return err
+		}
+	}
 	atomic.StoreUint32(&u.running, 1)
         go u.updateLoop()
+	return nil
 }
 
 // Stop is stop
-func (u Updater) Stop() {
+func (u *Updater) Stop() {
 	atomic.StoreUint32(&u.running, 0)
+	if u.metricsServer != nil {
+		u.metricsServer.Close()
+	}
 }
 
 // New is create updater
@@ -345,5 +414,6 @@ func New(context *contexter.Context, client *client.Client) (*Updater) {
         return &Updater {
                 client:  client,
                 context: context,
+                lastAliveMap: make(map[recordKey]bool),
         }
 }
@@ -0,0 +1,152 @@
+package watcher
+
+import (
+	"github.com/potix/belog"
+	"github.com/potix/pdns-record-updater/contexter"
+	"sort"
+	"strings"
+	"time"
+	"fmt"
+)
+
+// updateDependency is mark targets that are down while their parent is down as unreachable,
+// and notify once per root cause target instead of each dynamic record
+func (w *Watcher) updateDependency(watcherContext *contexter.Watcher, activeMaintenanceMap map[string]*contexter.Maintenance) {
+	targetMap := make(map[string]*contexter.Target)
+	for _, targetName := range watcherContext.GetTargetNameList() {
+		target, err := watcherContext.GetTarget(targetName)
+		if err != nil {
+			continue
+		}
+		targetMap[targetName] = target
+	}
+	aliveMap := make(map[string]bool, len(targetMap))
+	for targetName, target := range targetMap {
+		aliveMap[targetName] = target.GetAlive()
+	}
+	unreachableMap := make(map[string]bool)
+	for targetName, target := range targetMap {
+		unreachable := false
+		if !aliveMap[targetName] {
+			for _, parentTargetName := range target.ParentTargetNameList {
+				if parentAlive, ok := aliveMap[parentTargetName]; ok && !parentAlive {
+					unreachable = true
+					break
+				}
+			}
+		}
+		target.SetUnreachable(unreachable)
+		if unreachable {
+			unreachableMap[targetName] = true
+		}
+	}
+	// find root cause, it is target that is down but reachable
+	rootCauseMap := make(map[string][]string)
+	for targetName := range unreachableMap {
+		for _, rootCauseTargetName := range w.findRootCause(targetMap, aliveMap, unreachableMap, targetName) {
+			rootCauseMap[rootCauseTargetName] = append(rootCauseMap[rootCauseTargetName], targetName)
+		}
+	}
+	for targetName, target := range targetMap {
+		unreachableTargetNameList, rootCause := rootCauseMap[targetName]
+		if oldRootCause := target.SwapRootCause(rootCause); oldRootCause == rootCause {
+			continue
+		}
+		if maintenanceName, ok := targetMaintenanceName(activeMaintenanceMap, targetName); ok {
+			// suppress alert during maintenance
+			belog.Debug("target (%v) is in maintenance (%v), skip notify root cause", targetName, maintenanceName)
+			continue
+		}
+		sort.Strings(unreachableTargetNameList)
+		w.notifyRootCause(targetName, target, unreachableTargetNameList, rootCause)
+	}
+}
+
+// targetMaintenanceName is get name of active maintenance of target
+func targetMaintenanceName(activeMaintenanceMap map[string]*contexter.Maintenance, targetName string) (string, bool) {
+	for maintenanceName, maintenance := range activeMaintenanceMap {
+		if maintenance.TargetName == targetName {
+			return maintenanceName, true
+		}
+	}
+	return "", false
+}
+
+func (w *Watcher) findRootCause(targetMap map[string]*contexter.Target, aliveMap map[string]bool, unreachableMap map[string]bool, targetName string) ([]string) {
+	rootCauseTargetNameList := make([]string, 0, 1)
+	visitedMap := make(map[string]bool)
+	var walk func(targetName string)
+	walk = func(targetName string) {
+		if visitedMap[targetName] {
+			return
+		}
+		visitedMap[targetName] = true
+		target, ok := targetMap[targetName]
+		if !ok {
+			return
+		}
+		for _, parentTargetName := range target.ParentTargetNameList {
+			if aliveMap[parentTargetName] {
+				continue
+			}
+			if unreachableMap[parentTargetName] {
+				walk(parentTargetName)
+			} else if !visitedMap[parentTargetName] {
+				visitedMap[parentTargetName] = true
+				rootCauseTargetNameList = append(rootCauseTargetNameList, parentTargetName)
+			}
+		}
+	}
+	walk(targetName)
+	return rootCauseTargetNameList
+}
+
+func (w *Watcher) notifyRootCause(targetName string, target *contexter.Target, unreachableTargetNameList []string, rootCause bool) {
+	state := "recovered"
+	if rootCause {
+		state = "down"
+		belog.Notice("target (%v) is down, unreachable targets (%v)", targetName, strings.Join(unreachableTargetNameList, ", "))
+	} else {
+		belog.Notice("target (%v) is no longer root cause", targetName)
+	}
+	varList := []string{
+		"%(hostname)", w.hostname,
+		"%(time)", time.Now().Format("2006-01-02 15:04:05"),
+		"%(targetName)", targetName,
+		"%(dest)", target.Dest,
+		"%(alive)", fmt.Sprintf("%v", target.GetAlive()),
+		"%(unreachableTargetList)", strings.Join(unreachableTargetNameList, "\n"),
+		"%(detail)", target.GetDetail(),
+	}
+	subject := "%(hostname) %(targetName) %(dest): root cause " + state
+	body := "hostname: %(hostname)\ntarget: %(targetName) %(dest)\n%(time) root cause " + state + ", alive = %(alive)\n"
+	if rootCause {
+		body += "\nunreachable targets:\n%(unreachableTargetList)\n"
+	}
+	body += "\n-----\n%(detail)\n"
+	w.notifier.Notify(varList, subject, body)
+}
+
+// unreachableTargetNameList is get target name list of dynamic record that is unreachable,
+// and whether all down targets of dynamic record are unreachable
+func (w *Watcher) unreachableTargetNameList(watcherContext *contexter.Watcher, record *contexter.DynamicRecord) ([]string, bool) {
+	var unreachableTargetNameList []string
+	allUnreachable := true
+	for _, targetName := range record.TargetNameList {
+		target, err := watcherContext.GetTarget(targetName)
+		if err != nil {
+			// unknown target is down without root cause
+			allUnreachable = false
+			continue
+		}
+		if target.GetAlive() {
+			continue
+		}
+		if target.GetUnreachable() {
+			unreachableTargetNameList = append(unreachableTargetNameList, targetName)
+		} else {
+			allUnreachable = false
+		}
+	}
+	return unreachableTargetNameList, allUnreachable && len(unreachableTargetNameList) > 0
+}
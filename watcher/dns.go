@@ -0,0 +1,160 @@
+package watcher
+
+import (
+	"github.com/pkg/errors"
+	"github.com/potix/belog"
+	"github.com/miekg/dns"
+	"github.com/glenn-brown/golang-pkg-pcre/src/pkg/pcre"
+	"github.com/potix/pdns-record-updater/contexter"
+	"github.com/potix/pdns-record-updater/cacher"
+	"github.com/potix/pdns-record-updater/helper"
+	"crypto/tls"
+	"strings"
+	"net"
+	"time"
+	"fmt"
+)
+
+type dnsWatcher struct {
+	useRegexp        bool
+	ipPort           string
+	transport        string
+	name             string
+	qtype            uint16
+	recursionDesired bool
+	rcodeList        []string
+	retry            uint32
+	retryWait        uint32
+	timeout          uint32
+	regexp           *pcre.Regexp
+	regexpStr        string
+	tlsConfig        *tls.Config
+	lastErr          error
+}
+
+func (d *dnsWatcher) queryDNS() (bool, bool, error) {
+	client := &dns.Client{
+		Net:     d.transport,
+		Timeout: time.Duration(d.timeout) * time.Second,
+	}
+	if d.transport == "tcp-tls" {
+		client.TLSConfig = d.tlsConfig
+	}
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(d.name), d.qtype)
+	msg.RecursionDesired = d.recursionDesired
+	belog.Debug("dns (%v) (%v) (%v %v)", d.transport, d.ipPort, d.name, dns.TypeToString[d.qtype])
+	res, _, err := client.Exchange(msg, d.ipPort)
+	if err != nil {
+		return false, true, errors.Wrap(err, fmt.Sprintf("can not exchange message (%v)", d.ipPort))
+	}
+	rcode := dns.RcodeToString[res.Rcode]
+	match := false
+	for _, expected := range d.rcodeList {
+		if strings.ToUpper(expected) == rcode {
+			match = true
+			break
+		}
+	}
+	if !match {
+		belog.Debug("not match rcode (%v) (%v)", d.rcodeList, rcode)
+		return false, false, nil
+	}
+	if d.useRegexp {
+		match = false
+		for _, rr := range res.Answer {
+			if d.regexp.FindIndex([]byte(rr.String()), 0) != nil {
+				match = true
+				break
+			}
+		}
+		if !match {
+			belog.Debug("not match regexp (%v) (%v)", d.regexpStr, res.Answer)
+			return false, false, nil
+		}
+	}
+	belog.Debug("dns ok (%v)", d.ipPort)
+	return true, false, nil
+}
+
+func (d *dnsWatcher) isAlive() (bool) {
+	alive, err := retryProbe(d.ipPort, d.retry, d.retryWait, d.queryDNS)
+	d.lastErr = err
+	return alive
+}
+
+func (d *dnsWatcher) getLastError() (error) {
+	return d.lastErr
+}
+
+func dnsWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
+	var transport string
+	var defaultPort string
+	switch strings.ToUpper(target.DNSTransport) {
+	case "", "UDP":
+		transport = "udp"
+		defaultPort = "53"
+	case "TCP":
+		transport = "tcp"
+		defaultPort = "53"
+	case "TLS", "DOT":
+		transport = "tcp-tls"
+		defaultPort = "853"
+	default:
+		return nil, errors.Errorf("unsupported dns transport (%v)", target.DNSTransport)
+	}
+	ipPort := target.Dest
+	if _, _, err := net.SplitHostPort(ipPort); err != nil {
+		ipPort = net.JoinHostPort(ipPort, defaultPort)
+	}
+	qtypeStr := strings.ToUpper(target.DNSQtype)
+	if qtypeStr == "" {
+		qtypeStr = "A"
+	}
+	qtype, ok := dns.StringToType[qtypeStr]
+	if !ok {
+		return nil, errors.Errorf("unsupported dns qtype (%v)", target.DNSQtype)
+	}
+	rcodeList := target.DNSRcodeList
+	if rcodeList == nil || len(rcodeList) == 0 {
+		rcodeList = []string{ "NOERROR" }
+	}
+	newDNSWatcher := &dnsWatcher {
+		useRegexp:        false,
+		ipPort:           ipPort,
+		transport:        transport,
+		name:             target.DNSName,
+		qtype:            qtype,
+		recursionDesired: target.DNSRecursionDesired,
+		rcodeList:        rcodeList,
+		retry:            target.Retry,
+		retryWait:        target.RetryWait,
+		timeout:          target.Timeout,
+	}
+	if transport == "tcp-tls" {
+		// server name and ca file are used to verify server connected by ip address
+		tlsConfig, err := target.GetTLSConfig(func() (*tls.Config, error) {
+			return helper.NewTLSConfig(ipPort, &helper.HTTPClientOption{
+				TLSSkipVerify:     target.TLSSkipVerify,
+				TLSServerName:     target.TLSServerName,
+				TLSCAFile:         target.TLSCAFile,
+				TLSClientCertFile: target.TLSClientCertFile,
+				TLSClientKeyFile:  target.TLSClientKeyFile,
+			})
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("can not create tls config (%v)", ipPort))
+		}
+		newDNSWatcher.tlsConfig = tlsConfig
+	}
+	if target.Regexp != "" {
+		regexp, err := cacher.GetRegexpFromCache(target.Regexp, 0)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("can not get compiled regexp (%v)", target.Regexp))
+		}
+		newDNSWatcher.useRegexp = true
+		newDNSWatcher.regexp = regexp
+		newDNSWatcher.regexpStr = target.Regexp
+	}
+	return newDNSWatcher, nil
+}
@@ -0,0 +1,145 @@
+package watcher
+
+import (
+	"github.com/pkg/errors"
+	"github.com/potix/belog"
+	"github.com/potix/pdns-record-updater/contexter"
+	"os/exec"
+	"syscall"
+	"strings"
+	"sync"
+	"time"
+	"fmt"
+	"os"
+)
+
+type limitedBuffer struct {
+	mutex  sync.Mutex
+	buffer []byte
+	limit  uint32
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	remain := int(l.limit) - len(l.buffer)
+	if remain > 0 {
+		if len(p) > remain {
+			l.buffer = append(l.buffer, p[:remain]...)
+		} else {
+			l.buffer = append(l.buffer, p...)
+		}
+	}
+	// discard overflow, but never block the child process
+	return len(p), nil
+}
+
+func (l *limitedBuffer) String() (string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return strings.TrimSpace(string(l.buffer))
+}
+
+const (
+	defaultExecTimeout uint32        = 30
+	// wait for exit after kill, descendants that left process group may hold stdout open
+	execKillWait       time.Duration = 5 * time.Second
+)
+
+type execWatcher struct {
+	command   string
+	argList   []string
+	envList   []string
+	retry     uint32
+	retryWait uint32
+	timeout   uint32
+	resSize   uint32
+	detail    string
+	lastErr   error
+}
+
+func (e *execWatcher) runCommand() (bool, bool, error) {
+	output := &limitedBuffer{ limit: e.resSize }
+	cmd := exec.Command(e.command, e.argList...)
+	cmd.Env = append(os.Environ(), e.envList...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// run in own process group in order to kill descendants on timeout
+	cmd.SysProcAttr = &syscall.SysProcAttr{ Setpgid: true }
+	belog.Debug("exec (%v) (%v)", e.command, e.argList)
+	if err := cmd.Start(); err != nil {
+		e.detail = fmt.Sprintf("can not start: %v", err)
+		return false, false, errors.Wrap(err, fmt.Sprintf("can not start command (%v)", e.command))
+	}
+	waitChan := make(chan error, 1)
+	go func() {
+		waitChan <- cmd.Wait()
+	}()
+	timer := time.NewTimer(time.Duration(e.timeout) * time.Second)
+	defer timer.Stop()
+	var err error
+	select {
+	case err = <-waitChan:
+	case <-timer.C:
+		if killErr := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); killErr != nil {
+			belog.Error("can not kill process group (%v) (%v)", e.command, killErr)
+		}
+		select {
+		case <-waitChan:
+		case <-time.After(execKillWait):
+			belog.Error("command does not exit after kill (%v)", e.command)
+		}
+		e.detail = fmt.Sprintf("timeout: %v", output.String())
+		return false, true, errors.Errorf("command timeout (%v)", e.command)
+	}
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			belog.Debug("command failed (%v) (%v)", e.command, exitErr)
+			e.detail = fmt.Sprintf("%v: %v", exitErr, output.String())
+			return false, false, nil
+		}
+		e.detail = fmt.Sprintf("%v: %v", err, output.String())
+		return false, true, errors.Wrap(err, fmt.Sprintf("can not wait command (%v)", e.command))
+	}
+	e.detail = output.String()
+	belog.Debug("exec ok (%v)", e.command)
+	return true, false, nil
+}
+
+func (e *execWatcher) isAlive() (bool) {
+	alive, err := retryProbe(e.command, e.retry, e.retryWait, e.runCommand)
+	e.lastErr = err
+	return alive
+}
+
+func (e *execWatcher) getDetail() (string) {
+	return e.detail
+}
+
+func (e *execWatcher) getLastError() (error) {
+	return e.lastErr
+}
+
+func execWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
+	envList := make([]string, 0, len(target.ExecEnvMap))
+	for key, value := range target.ExecEnvMap {
+		envList = append(envList, fmt.Sprintf("%v=%v", key, value))
+	}
+	timeout := target.Timeout
+	if timeout == 0 {
+		timeout = defaultExecTimeout
+	}
+	resSize := target.ResSize
+	if resSize == 0 {
+		resSize = 1024
+	}
+	return &execWatcher {
+		command:   target.Dest,
+		argList:   target.ExecArgList,
+		envList:   envList,
+		retry:     target.Retry,
+		retryWait: target.RetryWait,
+		timeout:   timeout,
+		resSize:   resSize,
+	}, nil
+}
@@ -0,0 +1,109 @@
+package watcher
+
+import (
+	"github.com/pkg/errors"
+	"github.com/potix/belog"
+	"github.com/potix/pdns-record-updater/contexter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+	"fmt"
+)
+
+type grpcWatcher struct {
+	ipPort        string
+	serviceName   string
+	useTLS        bool
+	serverName    string
+	retry         uint32
+	retryWait     uint32
+	timeout       uint32
+	tlsSkipVerify bool
+	detail        string
+	lastErr       error
+}
+
+func (g *grpcWatcher) checkHealth() (bool, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(g.timeout) * time.Second)
+	defer cancel()
+	var transportCredentials credentials.TransportCredentials
+	if g.useTLS {
+		belog.Debug("grpc tls (%v) (%v)", g.ipPort, g.serviceName)
+		transportCredentials = credentials.NewTLS(&tls.Config{ ServerName: g.serverName, InsecureSkipVerify: g.tlsSkipVerify })
+	} else {
+		belog.Debug("grpc (%v) (%v)", g.ipPort, g.serviceName)
+		transportCredentials = insecure.NewCredentials()
+	}
+	conn, err := grpc.DialContext(ctx, g.ipPort, grpc.WithTransportCredentials(transportCredentials), grpc.WithBlock())
+	if err != nil {
+		g.detail = fmt.Sprintf("can not connect: %v", err)
+		return false, true, errors.Wrap(err, fmt.Sprintf("can not connect (%v)", g.ipPort))
+	}
+	defer conn.Close()
+	res, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{ Service: g.serviceName })
+	if err != nil {
+		g.detail = fmt.Sprintf("health check failed: %v", err)
+		switch status.Code(err) {
+		case codes.NotFound, codes.Unimplemented:
+			// unknown service or no health service, retry does not help
+			return false, false, errors.Wrap(err, fmt.Sprintf("can not check health (%v) (%v)", g.ipPort, g.serviceName))
+		default:
+			return false, true, errors.Wrap(err, fmt.Sprintf("can not check health (%v) (%v)", g.ipPort, g.serviceName))
+		}
+	}
+	g.detail = fmt.Sprintf("status = %v", res.Status)
+	if res.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		belog.Debug("not serving (%v) (%v) (%v)", g.ipPort, g.serviceName, res.Status)
+		return false, false, nil
+	}
+	belog.Debug("grpc ok (%v)", g.ipPort)
+	return true, false, nil
+}
+
+func (g *grpcWatcher) isAlive() (bool) {
+	alive, err := retryProbe(g.ipPort, g.retry, g.retryWait, g.checkHealth)
+	g.lastErr = err
+	return alive
+}
+
+func (g *grpcWatcher) getDetail() (string) {
+	return g.detail
+}
+
+func (g *grpcWatcher) getLastError() (error) {
+	return g.lastErr
+}
+
+const defaultGRPCTimeout uint32 = 10
+
+func grpcWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
+	host, _, err := net.SplitHostPort(target.Dest)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("can not split host and port (%v)", target.Dest))
+	}
+	serverName := target.TLSServerName
+	if serverName == "" {
+		serverName = host
+	}
+	timeout := target.Timeout
+	if timeout == 0 {
+		timeout = defaultGRPCTimeout
+	}
+	return &grpcWatcher {
+		ipPort:        target.Dest,
+		serviceName:   target.GRPCServiceName,
+		useTLS:        target.GRPCTLS,
+		serverName:    serverName,
+		retry:         target.Retry,
+		retryWait:     target.RetryWait,
+		timeout:       timeout,
+		tlsSkipVerify: target.TLSSkipVerify,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package watcher
+
+import (
+	"github.com/potix/pdns-record-updater/contexter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"strings"
+	"testing"
+)
+
+func startHealthServer(t *testing.T) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can not listen: %v", err)
+	}
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("serving", grpc_health_v1.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("notServing", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener)
+	return listener.Addr().String(), server.Stop
+}
+
+func TestGRPCWatcher(t *testing.T) {
+	addr, stop := startHealthServer(t)
+	defer stop()
+	testCaseList := []struct {
+		serviceName string
+		alive       bool
+		detail      string
+	}{
+		{ serviceName: "",           alive: true,  detail: "status = SERVING" },
+		{ serviceName: "serving",    alive: true,  detail: "status = SERVING" },
+		{ serviceName: "notServing", alive: false, detail: "status = NOT_SERVING" },
+		{ serviceName: "unknown",    alive: false, detail: "NotFound" },
+	}
+	for _, testCase := range testCaseList {
+		protoWatcher, err := grpcWatcherNew(&contexter.Target{
+			Dest:            addr,
+			GRPCServiceName: testCase.serviceName,
+			Timeout:         5,
+			Retry:           2,
+		})
+		if err != nil {
+			t.Fatalf("can not create grpc watcher: %v", err)
+		}
+		if alive := protoWatcher.isAlive(); alive != testCase.alive {
+			t.Errorf("service (%v): alive = %v, want %v", testCase.serviceName, alive, testCase.alive)
+		}
+		if detail := protoWatcher.(protoWatcherDetailIf).getDetail(); !strings.Contains(detail, testCase.detail) {
+			t.Errorf("service (%v): detail = %v, want %v", testCase.serviceName, detail, testCase.detail)
+		}
+	}
+}
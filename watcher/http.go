@@ -4,18 +4,30 @@ import (
 	"github.com/pkg/errors"
         "github.com/potix/belog"
 	"github.com/glenn-brown/golang-pkg-pcre/src/pkg/pcre"
+	"github.com/tidwall/gjson"
 	"github.com/potix/pdns-record-updater/contexter"
 	"github.com/potix/pdns-record-updater/cacher"
 	"github.com/potix/pdns-record-updater/helper"
 	"net/http"
 	"net/url"
-	"time"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"strconv"
 	"fmt"
 	"strings"
 )
 
+type jsonAssertion struct {
+	path     string
+	operator string
+	value    string
+	regexp   *pcre.Regexp
+}
+
 type httpWatcher struct {
         useRegexp     bool
+        useJSON           bool
         url           string
         method        string
         retry         uint32
@@ -25,26 +37,109 @@ type httpWatcher struct {
         regexpStr     string
         regexp        *pcre.Regexp
         resSize       uint32
-	tlsSkipVerify bool
+	headerMap         map[string]string
+	body              string
+	clientOption      *helper.HTTPClientOption
+	jsonAssertionList []*jsonAssertion
+	detail            string
+	lastErr           error
+}
+
+func (j *jsonAssertion) evaluate(body []byte) (bool) {
+	result := gjson.GetBytes(body, j.path)
+	switch j.operator {
+	case "EXISTS":
+		return result.Exists()
+	case "", "EQ":
+		return result.Exists() && result.String() == j.value
+	case "NE":
+		return !result.Exists() || result.String() != j.value
+	case "REGEXP":
+		return result.Exists() && j.regexp.FindIndex([]byte(result.String()), 0) != nil
+	case "GT", "GE", "LT", "LE":
+		if !result.Exists() {
+			return false
+		}
+		expected, err := strconv.ParseFloat(j.value, 64)
+		if err != nil {
+			belog.Error("can not parse value as number (%v)", j.value)
+			return false
+		}
+		actual := result.Float()
+		switch j.operator {
+		case "GT":
+			return actual > expected
+		case "GE":
+			return actual >= expected
+		case "LT":
+			return actual < expected
+		case "LE":
+			return actual <= expected
+		}
+	}
+	return false
+}
+
+func (h *httpWatcher) checkJSON(body io.Reader) (bool, bool, error) {
+	if h.resSize == 0 {
+		h.resSize = 1024 * 1024
+	}
+	// read one extra byte to detect that body is cut
+	rb, err := ioutil.ReadAll(io.LimitReader(body, int64(h.resSize) + 1))
+	if err != nil {
+		h.detail = fmt.Sprintf("can not read body (%v)", err)
+		return false, true, errors.Wrap(err, fmt.Sprintf("can not read body (%v)", h.url))
+	}
+	if len(rb) > int(h.resSize) {
+		belog.Debug("response exceeds resSize (%v) (%v)", h.url, h.resSize)
+		h.detail = fmt.Sprintf("response exceeds resSize (%v)", h.resSize)
+		return false, false, nil
+	}
+	if !gjson.ValidBytes(rb) {
+		belog.Debug("invalid json (%v) (%v)", h.url, string(rb))
+		h.detail = "invalid json"
+		return false, false, nil
+	}
+	for _, jsonAssertion := range h.jsonAssertionList {
+		if !jsonAssertion.evaluate(rb) {
+			belog.Debug("not match json assertion (%v %v %v) (%v)", jsonAssertion.path, jsonAssertion.operator, jsonAssertion.value, string(rb))
+			h.detail = fmt.Sprintf("not match json assertion (%v %v %v), actual = %v",
+				jsonAssertion.path, strings.ToLower(jsonAssertion.operator), jsonAssertion.value, gjson.GetBytes(rb, jsonAssertion.path).String())
+			return false, false, nil
+		}
+	}
+	return true, false, nil
 }
 
 func (h *httpWatcher) reqHTTP() (bool, bool, error) {
+	h.detail = ""
         u, err := url.Parse(h.url)
 	if err != nil {
 		return false, false, errors.Errorf("can not parse url (%v)", h.url)
 	}
-	httpClient := helper.NewHTTPClient(u.Scheme, u.Host, h.tlsSkipVerify, h.timeout)
+	httpClient, err := helper.NewHTTPClient(u.Scheme, u.Host, h.clientOption)
+	if err != nil {
+		return false, false, errors.Wrap(err, fmt.Sprintf("can not create http client (%v)", h.url))
+	}
 	method := strings.ToUpper(h.method)
 	if method == "" {
 		method = "GET"
 	}
-	if method != "GET" && method != "HEAD" {
-		return false, false, errors.Errorf("unsupported method (%v)", method)
+	var body io.Reader
+	if h.body != "" {
+		body = strings.NewReader(h.body)
 	}
-	request, err := http.NewRequest(method, h.url, nil)
+	request, err := http.NewRequest(method, h.url, body)
 	if err != nil {
 		return false, false, errors.Wrap(err, fmt.Sprintf("can not create request (%v)", h.url))
 	}
+	for key, value := range h.headerMap {
+		if strings.ToUpper(key) == "HOST" {
+			request.Host = value
+			continue
+		}
+		request.Header.Set(key, value)
+	}
 	res, err := httpClient.Do(request)
 	if err != nil {
 		return false, true, errors.Wrap(err, fmt.Sprintf("can not get url (%v)", h.url))
@@ -60,6 +155,7 @@ func (h *httpWatcher) reqHTTP() (bool, bool, error) {
 		}
 		if !match {
 			belog.Debug("not match status (%v)", h.status)
+			h.detail = fmt.Sprintf("unexpected status %v", res.StatusCode)
 			return false, false, nil
 		}
 	}
@@ -78,37 +174,68 @@ func (h *httpWatcher) reqHTTP() (bool, bool, error) {
 			return false, false, nil
 		}
 	}
+	if h.useJSON {
+		alive, retryable, err := h.checkJSON(res.Body)
+		if !alive {
+			return alive, retryable, err
+		}
+	}
 	belog.Debug("http ok (%v)", h.url)
 	return true, false, nil
 }
 
 func (h *httpWatcher) isAlive() (bool) {
-	var i uint32
-	for i = 0; i <= h.retry; i++ {
-		alive, retryable, err := h.reqHTTP()
-		if err != nil {
-			belog.Error("%v", err)
-		}
-		if !retryable {
-			return alive
-		}
-		if h.retryWait > 0 {
-			time.Sleep(time.Duration(h.retryWait) * time.Second)
-		}
+	alive, err := retryProbe(h.url, h.retry, h.retryWait, h.reqHTTP)
+	h.lastErr = err
+	return alive
+}
+
+func (h *httpWatcher) getDetail() (string) {
+	return h.detail
+}
+
+func (h *httpWatcher) getLastError() (error) {
+	return h.lastErr
+}
+
+func newHTTPClientOption(target *contexter.Target) (*helper.HTTPClientOption) {
+	clientOption := &helper.HTTPClientOption {
+		TLSSkipVerify:     target.TLSSkipVerify,
+		TLSServerName:     target.TLSServerName,
+		TLSCAFile:         target.TLSCAFile,
+		TLSClientCertFile: target.TLSClientCertFile,
+		TLSClientKeyFile:  target.TLSClientKeyFile,
+		RedirectPolicy:    target.HTTPRedirectPolicy,
+		Timeout:           target.Timeout,
 	}
-	belog.Error("retry count is exceeded limit (%v)", h.url)
-	return false
+	u, err := url.Parse(target.Dest)
+	if err != nil || u.Scheme != "https" {
+		return clientOption
+	}
+	// watcher is created for each probe, so reuse tls config parsed once
+	tlsConfig, err := target.GetTLSConfig(func() (*tls.Config, error) {
+		return helper.NewTLSConfig(u.Host, clientOption)
+	})
+	if err != nil {
+		// NewHTTPClient reports error
+		return clientOption
+	}
+	clientOption.TLSConfig = tlsConfig
+	return clientOption
 }
 
 func httpWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
         return &httpWatcher {
                 useRegexp:     false,
                 url:           target.Dest,
+                method:        target.HTTPMethod,
                 retry:         target.Retry,
                 retryWait:     target.RetryWait,
                 timeout:       target.Timeout,
                 status:        target.HTTPStatusList,
-		tlsSkipVerify: target.TLSSkipVerify,
+		headerMap:     target.HTTPHeaderMap,
+		body:          target.HTTPBody,
+		clientOption:  newHTTPClientOption(target),
         }, nil
 }
 
@@ -129,6 +256,41 @@ func httpRegexpWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
                 regexpStr:     target.Regexp,
                 regexp:        regexp,
                 resSize:       target.ResSize,
-		tlsSkipVerify: target.TLSSkipVerify,
+		headerMap:     target.HTTPHeaderMap,
+		body:          target.HTTPBody,
+		clientOption:  newHTTPClientOption(target),
+        }, nil
+}
+
+func httpJSONWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
+	jsonAssertionList := make([]*jsonAssertion, 0, len(target.HTTPJSONAssertionList))
+	for _, targetJSONAssertion := range target.HTTPJSONAssertionList {
+		newJSONAssertion := &jsonAssertion {
+			path:     targetJSONAssertion.Path,
+			operator: strings.ToUpper(targetJSONAssertion.Operator),
+			value:    targetJSONAssertion.Value,
+		}
+		if newJSONAssertion.operator == "REGEXP" {
+			regexp, err := cacher.GetRegexpFromCache(targetJSONAssertion.Value, 0)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("can not get compiled regexp (%v)", targetJSONAssertion.Value))
+			}
+			newJSONAssertion.regexp = regexp
+		}
+		jsonAssertionList = append(jsonAssertionList, newJSONAssertion)
+	}
+        return &httpWatcher {
+                useJSON:           true,
+                url:               target.Dest,
+                method:            target.HTTPMethod,
+                retry:             target.Retry,
+                retryWait:         target.RetryWait,
+                timeout:           target.Timeout,
+                status:            target.HTTPStatusList,
+                resSize:           target.ResSize,
+		headerMap:         target.HTTPHeaderMap,
+		body:              target.HTTPBody,
+		clientOption:      newHTTPClientOption(target),
+		jsonAssertionList: jsonAssertionList,
         }, nil
 }
@@ -22,29 +22,20 @@ type icmpWatcher struct {
 	retryWait  uint32
 	timeout    uint32
 	resSize    uint32
+	count      uint32
+	interval   uint32
+	maxLoss    uint32
+	maxAvgRTT  uint32
+	metric     *contexter.TargetMetric
+	detail     string
+	lastErr    error
 }
 
 func (i *icmpWatcher) getSeqNumber() (uint32) {
 	return atomic.AddUint32(&seq, 1);
 }
 
-func (i *icmpWatcher) sendIcmp(ip net.IP) (bool, bool, error) {
-	var ipv int
-	var conn *icmp.PacketConn
-	var err error
-	if (ip.To4() != nil) {
-		belog.Debug("icmp ipv4 (%v)", i.ipAddr)
-		ipv = 4
-		conn, err = icmp.ListenPacket("ip4:icmp", "0.0.0.0")
-	} else {
-		belog.Debug("icmp ipv6 (%v)", i.ipAddr)
-		ipv = 6
-		conn, err = icmp.ListenPacket("ip6:icmp", "::")
-	}
-	if err != nil {
-		return false, true, errors.Wrap(err, fmt.Sprintf("can not create icmp connection (%v)", i.ipAddr))
-	}
-	defer conn.Close()
+func (i *icmpWatcher) sendIcmp(conn *icmp.PacketConn, ipv int, ip net.IP) (time.Duration, bool, error) {
 	echoReq := &icmp.Echo{
 		ID:   os.Getpid() & 0xFFFF,
 		Seq:  int(i.getSeqNumber() & 0xFFFF),
@@ -66,13 +57,14 @@ func (i *icmpWatcher) sendIcmp(ip net.IP) (bool, bool, error) {
 	}
 	wb, err := wm.Marshal(nil)
 	if err != nil {
-		return false, false, errors.Wrap(err, fmt.Sprintf("can not marshal message (%v)", wm))
+		return 0, false, errors.Wrap(err, fmt.Sprintf("can not marshal message (%v)", wm))
 	}
+	start := time.Now()
 	if _, err := conn.WriteTo(wb, &net.IPAddr{IP: ip}); err != nil {
-		return false, true, errors.Wrap(err, fmt.Sprintf("can not write message (%v)", i.ipAddr))
+		return 0, true, errors.Wrap(err, fmt.Sprintf("can not write message (%v)", i.ipAddr))
 	}
-	if err := conn.SetReadDeadline(time.Now().Add(time.Duration(i.timeout) * time.Second)); err != nil {
-		return false, false, errors.Wrap(err, fmt.Sprintf("can not set deadline (%v)", i.ipAddr))
+	if err := conn.SetReadDeadline(start.Add(time.Duration(i.timeout) * time.Second)); err != nil {
+		return 0, false, errors.Wrap(err, fmt.Sprintf("can not set deadline (%v)", i.ipAddr))
 	}
 	if i.resSize == 0 {
 		i.resSize = 512
@@ -81,7 +73,7 @@ func (i *icmpWatcher) sendIcmp(ip net.IP) (bool, bool, error) {
 Read:
 	rlen, _ /* peer */, err := conn.ReadFrom(rb)
 	if err != nil {
-		return false, true, errors.Wrap(err, fmt.Sprintf("can not read response (%v)", i.ipAddr))
+		return 0, true, errors.Wrap(err, fmt.Sprintf("can not read response (%v)", i.ipAddr))
 	}
 	var proto int
 	switch ipv {
@@ -108,40 +100,117 @@ Read:
 		belog.Debug("unexpected icmp type (%v)", rm.Type)
 		goto Read
 	}
-	belog.Debug("icmp ok (%v)", i.ipAddr)
+	rtt := time.Since(start)
+	belog.Debug("icmp ok (%v) (%v)", i.ipAddr, rtt)
+	return rtt, false, nil
+}
+
+func (i *icmpWatcher) sendIcmpBurst(ip net.IP) (bool, bool, error) {
+	var ipv int
+	var conn *icmp.PacketConn
+	var err error
+	if (ip.To4() != nil) {
+		belog.Debug("icmp ipv4 (%v)", i.ipAddr)
+		ipv = 4
+		conn, err = icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	} else {
+		belog.Debug("icmp ipv6 (%v)", i.ipAddr)
+		ipv = 6
+		conn, err = icmp.ListenPacket("ip6:icmp", "::")
+	}
+	if err != nil {
+		return false, true, errors.Wrap(err, fmt.Sprintf("can not create icmp connection (%v)", i.ipAddr))
+	}
+	defer conn.Close()
+	var received uint32
+	var minRTT, maxRTT, totalRTT time.Duration
+	var j uint32
+	for j = 0; j < i.count; j++ {
+		if j > 0 && i.interval > 0 {
+			time.Sleep(time.Duration(i.interval) * time.Millisecond)
+		}
+		rtt, retryable, err := i.sendIcmp(conn, ipv, ip)
+		if err != nil {
+			if !retryable {
+				return false, false, err
+			}
+			belog.Debug("%v", err)
+			continue
+		}
+		if received == 0 || rtt < minRTT {
+			minRTT = rtt
+		}
+		if rtt > maxRTT {
+			maxRTT = rtt
+		}
+		totalRTT += rtt
+		received++
+	}
+	i.metric = &contexter.TargetMetric{
+		LossPercent: float64(i.count - received) * 100 / float64(i.count),
+	}
+	if received > 0 {
+		i.metric.MinRTT = float64(minRTT) / float64(time.Millisecond)
+		i.metric.AvgRTT = float64(totalRTT) / float64(received) / float64(time.Millisecond)
+		i.metric.MaxRTT = float64(maxRTT) / float64(time.Millisecond)
+		i.metric.Latency = i.metric.AvgRTT
+	}
+	i.detail = fmt.Sprintf("%v packets transmitted, %v received, %.1f%% packet loss, rtt min/avg/max = %.3f/%.3f/%.3f ms",
+		i.count, received, i.metric.LossPercent, i.metric.MinRTT, i.metric.AvgRTT, i.metric.MaxRTT)
+	if received == 0 {
+		return false, true, errors.Errorf("no echo reply (%v)", i.ipAddr)
+	}
+	if i.maxLoss > 0 && i.metric.LossPercent > float64(i.maxLoss) {
+		belog.Debug("packet loss is exceeded limit (%v) (%v > %v)", i.ipAddr, i.metric.LossPercent, i.maxLoss)
+		return false, false, nil
+	}
+	if i.maxAvgRTT > 0 && i.metric.AvgRTT > float64(i.maxAvgRTT) {
+		belog.Debug("average rtt is exceeded limit (%v) (%v > %v)", i.ipAddr, i.metric.AvgRTT, i.maxAvgRTT)
+		return false, false, nil
+	}
 	return true, false, nil
 }
 
 func (i *icmpWatcher) isAlive() (bool) {
 	ip := net.ParseIP(i.ipAddr)
 	if ip == nil {
-		belog.Error("can not parse ip address (%v)", i.ipAddr)
+		i.lastErr = errors.Errorf("can not parse ip address (%v)", i.ipAddr)
+		belog.Error("%v", i.lastErr)
 		return false
 	}
-	var j uint32
-	for j = 0; j <= i.retry; j++ {
-                alive, retryable, err := i.sendIcmp(ip)
-                if err != nil {
-                        belog.Error("%v", err)
-                }
-                if !retryable {
-                        return alive
-                }
-                if i.retryWait > 0 {
-                        time.Sleep(time.Duration(i.retryWait) * time.Second)
-                }
-	}
-        belog.Error("retry count is exceeded limit (%v)", i.ipAddr)
-	return false
+	alive, err := retryProbe(i.ipAddr, i.retry, i.retryWait, func() (bool, bool, error) {
+		return i.sendIcmpBurst(ip)
+	})
+	i.lastErr = err
+	return alive
+}
+
+func (i *icmpWatcher) getDetail() (string) {
+	return i.detail
+}
+
+func (i *icmpWatcher) getLastError() (error) {
+	return i.lastErr
+}
+
+func (i *icmpWatcher) getMetric() (*contexter.TargetMetric) {
+	return i.metric
 }
 
 func icmpWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
+	count := target.ICMPCount
+	if count == 0 {
+		count = 1
+	}
 	return &icmpWatcher {
 		ipAddr:    target.Dest,
 		retry:     target.Retry,
 		retryWait: target.RetryWait,
 		timeout:   target.Timeout,
 		resSize:   target.ResSize,
+		count:     count,
+		interval:  target.ICMPInterval,
+		maxLoss:   target.ICMPMaxLoss,
+		maxAvgRTT: target.ICMPMaxAvgRTT,
 	}, nil
 }
-
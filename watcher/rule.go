@@ -0,0 +1,44 @@
+package watcher
+
+import (
+	"github.com/pkg/errors"
+	"github.com/potix/pdns-record-updater/contexter"
+)
+
+type ruleEnv struct {
+	watcherContext *contexter.Watcher
+}
+
+func (r *ruleEnv) GetAlive(targetName string) (bool, error) {
+	target, err := r.watcherContext.GetTarget(targetName)
+	if err != nil {
+		// target that is not found is regarded as down
+		return false, nil
+	}
+	return target.GetAlive(), nil
+}
+
+func (r *ruleEnv) GetAttribute(targetName string, attribute string) (float64, error) {
+	target, err := r.watcherContext.GetTarget(targetName)
+	if err != nil {
+		return 0, err
+	}
+	metric := target.GetMetric()
+	if metric == nil {
+		return 0, errors.Errorf("no metric of target (%v)", targetName)
+	}
+	switch attribute {
+	case "latency":
+		return metric.Latency, nil
+	case "loss":
+		return metric.LossPercent, nil
+	case "minRtt":
+		return metric.MinRTT, nil
+	case "avgRtt":
+		return metric.AvgRTT, nil
+	case "maxRtt":
+		return metric.MaxRTT, nil
+	default:
+		return 0, errors.Errorf("unsupported attribute (%v)", attribute)
+	}
+}
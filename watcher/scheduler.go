@@ -0,0 +1,253 @@
+package watcher
+
+import (
+	"github.com/potix/belog"
+	"github.com/potix/pdns-record-updater/contexter"
+	"github.com/potix/pdns-record-updater/metrics"
+	"container/heap"
+	"math/rand"
+	"sync/atomic"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWorkerCount    uint32        = 32
+	schedulerSyncInterval time.Duration = time.Second
+	schedulerMaxWait      time.Duration = 100 * time.Millisecond
+)
+
+type scheduleItem struct {
+	targetName string
+	target     *contexter.Target
+	protocol   string
+	baseRun    time.Time
+	nextRun    time.Time
+	index      int
+}
+
+type scheduleHeap []*scheduleItem
+
+func (s scheduleHeap) Len() int {
+	return len(s)
+}
+
+func (s scheduleHeap) Less(i, j int) bool {
+	return s[i].nextRun.Before(s[j].nextRun)
+}
+
+func (s scheduleHeap) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+	s[i].index = i
+	s[j].index = j
+}
+
+func (s *scheduleHeap) Push(x interface{}) {
+	item := x.(*scheduleItem)
+	item.index = len(*s)
+	*s = append(*s, item)
+}
+
+func (s *scheduleHeap) Pop() interface{} {
+	old := *s
+	n := len(old)
+	item := old[n - 1]
+	old[n - 1] = nil
+	item.index = -1
+	*s = old[:n - 1]
+	return item
+}
+
+type scheduleTask struct {
+	targetName  string
+	target      *contexter.Target
+	scheduledAt time.Time
+}
+
+type scheduler struct {
+	watcher     *Watcher
+	running     uint32
+	workerCount uint32
+	heap        scheduleHeap
+	itemMap     map[string]*scheduleItem
+	taskChan    chan *scheduleTask
+	loopDone    chan bool
+	random      *rand.Rand
+	waitGroup   *sync.WaitGroup
+}
+
+func (s *scheduler) jitter(target *contexter.Target) (time.Duration) {
+	if target.WatchJitter == 0 {
+		return 0
+	}
+	return time.Duration(s.random.Int63n(int64(target.WatchJitter))) * time.Millisecond
+}
+
+func (s *scheduler) firstRun(target *contexter.Target, now time.Time) (time.Time) {
+	interval := target.GetInterval()
+	if lastProbeTime := target.GetLastProbeTime(); !lastProbeTime.IsZero() {
+		nextRun := lastProbeTime.Add(interval)
+		if nextRun.After(now) {
+			return nextRun
+		}
+	}
+	// spread first run over interval, so that targets with same interval do not fire in lockstep
+	if interval <= 0 {
+		return now
+	}
+	return now.Add(time.Duration(s.random.Int63n(int64(interval))))
+}
+
+func (s *scheduler) sync(watcherContext *contexter.Watcher, now time.Time) {
+	targetNameList := watcherContext.GetTargetNameList()
+	existMap := make(map[string]bool, len(targetNameList))
+	for _, targetName := range targetNameList {
+		target, err := watcherContext.GetTarget(targetName)
+		if err != nil {
+			continue
+		}
+		existMap[targetName] = true
+		protocol := strings.ToLower(target.Protocol)
+		item, ok := s.itemMap[targetName]
+		if ok && item.protocol != protocol {
+			// metrics are labeled with protocol
+			metrics.DeleteProbe(targetName, item.protocol)
+			item.protocol = protocol
+		}
+		if ok && item.target == target {
+			continue
+		}
+		if ok {
+			// replaced target
+			heap.Remove(&s.heap, item.index)
+		}
+		baseRun := s.firstRun(target, now)
+		newItem := &scheduleItem{
+			targetName: targetName,
+			target:     target,
+			protocol:   protocol,
+			baseRun:    baseRun,
+			nextRun:    baseRun.Add(s.jitter(target)),
+		}
+		heap.Push(&s.heap, newItem)
+		s.itemMap[targetName] = newItem
+	}
+	for targetName, item := range s.itemMap {
+		if !existMap[targetName] {
+			// deleted target
+			heap.Remove(&s.heap, item.index)
+			delete(s.itemMap, targetName)
+			metrics.DeleteProbe(targetName, item.protocol)
+		}
+	}
+}
+
+func (s *scheduler) missDeadline(targetName string, target *contexter.Target, reason string) {
+	target.IncrementMissedDeadlineCount()
+	belog.Warn("missed deadline of target (%v) (%v): %v", targetName, target.Dest, reason)
+}
+
+func (s *scheduler) dispatch(item *scheduleItem, now time.Time) {
+	target := item.target
+	if !target.CompareAndSwapProgress(false, true) {
+		s.missDeadline(item.targetName, target, "previous probe is still in progress")
+	} else {
+		task := &scheduleTask{
+			targetName:  item.targetName,
+			target:      target,
+			scheduledAt: item.nextRun,
+		}
+		select {
+		case s.taskChan <- task:
+		default:
+			target.SetProgress(false)
+			s.missDeadline(item.targetName, target, "all workers are busy")
+		}
+	}
+	// jitter is applied to dispatch time only, so that it does not accumulate
+	interval := target.GetInterval()
+	baseRun := item.baseRun.Add(interval)
+	if baseRun.Before(now) {
+		// skip runs that are already past
+		baseRun = now.Add(interval)
+	}
+	item.baseRun = baseRun
+	item.nextRun = baseRun.Add(s.jitter(target))
+	heap.Push(&s.heap, item)
+}
+
+func (s *scheduler) scheduleLoop() {
+	defer close(s.loopDone)
+	defer close(s.taskChan)
+	var lastSync time.Time
+	for atomic.LoadUint32(&s.running) == 1 {
+		now := time.Now()
+		if now.Sub(lastSync) >= schedulerSyncInterval {
+			s.sync(s.watcher.context.GetWatcher(), now)
+			lastSync = now
+		}
+		if s.heap.Len() == 0 {
+			time.Sleep(schedulerMaxWait)
+			continue
+		}
+		item := s.heap[0]
+		if wait := item.nextRun.Sub(now); wait > 0 {
+			if wait > schedulerMaxWait {
+				wait = schedulerMaxWait
+			}
+			time.Sleep(wait)
+			continue
+		}
+		heap.Pop(&s.heap)
+		s.dispatch(item, now)
+	}
+}
+
+func (s *scheduler) worker() {
+	defer s.waitGroup.Done()
+	for task := range s.taskChan {
+		if delay := time.Since(task.scheduledAt); delay > task.target.GetInterval() {
+			s.missDeadline(task.targetName, task.target, "probe started late")
+		}
+		s.watcher.targetWatch(task.targetName, task.target, false)
+		task.target.SetProgress(false)
+		s.watcher.requestUpdate()
+	}
+}
+
+func (s *scheduler) start() {
+	atomic.StoreUint32(&s.running, 1)
+	var i uint32
+	for i = 0; i < s.workerCount; i++ {
+		s.waitGroup.Add(1)
+		go s.worker()
+	}
+	go s.scheduleLoop()
+}
+
+func (s *scheduler) stop() {
+	if !atomic.CompareAndSwapUint32(&s.running, 1, 0) {
+		return
+	}
+	// workers exit after schedule loop closes task channel and in-flight probes finish
+	<-s.loopDone
+	s.waitGroup.Wait()
+}
+
+func newScheduler(watcher *Watcher, workerCount uint32) (*scheduler) {
+	if workerCount == 0 {
+		workerCount = defaultWorkerCount
+	}
+	return &scheduler{
+		watcher:     watcher,
+		running:     0,
+		workerCount: workerCount,
+		heap:        make(scheduleHeap, 0),
+		itemMap:     make(map[string]*scheduleItem),
+		taskChan:    make(chan *scheduleTask, workerCount),
+		loopDone:    make(chan bool),
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		waitGroup:   new(sync.WaitGroup),
+	}
+}
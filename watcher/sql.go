@@ -0,0 +1,202 @@
+package watcher
+
+import (
+	"github.com/pkg/errors"
+	"github.com/potix/belog"
+	"github.com/potix/pdns-record-updater/contexter"
+	"github.com/potix/pdns-record-updater/helper"
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"net/url"
+	"net"
+	"sync"
+	"time"
+	"fmt"
+)
+
+type sqlWatcher struct {
+	driverName    string
+	ipPort        string
+	dataSource    string
+	query         string
+	expectedValue string
+	retry         uint32
+	retryWait     uint32
+	timeout       uint32
+	detail        string
+	lastErr       error
+}
+
+func (s *sqlWatcher) querySQL() (bool, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.timeout) * time.Second)
+	defer cancel()
+	belog.Debug("%v (%v)", s.driverName, s.ipPort)
+	db, err := sql.Open(s.driverName, s.dataSource)
+	if err != nil {
+		s.detail = fmt.Sprintf("can not open: %v", err)
+		return false, false, errors.Wrap(err, fmt.Sprintf("can not open database (%v)", s.ipPort))
+	}
+	defer db.Close()
+	// ping forces a real handshake including authentication
+	if err := db.PingContext(ctx); err != nil {
+		s.detail = fmt.Sprintf("can not connect: %v", err)
+		return false, true, errors.Wrap(err, fmt.Sprintf("can not connect (%v)", s.ipPort))
+	}
+	if s.query == "" {
+		s.detail = ""
+		belog.Debug("%v ok (%v)", s.driverName, s.ipPort)
+		return true, false, nil
+	}
+	var value sql.NullString
+	if err := db.QueryRowContext(ctx, s.query).Scan(&value); err != nil {
+		s.detail = fmt.Sprintf("query failed: %v", err)
+		return false, false, errors.Wrap(err, fmt.Sprintf("can not query (%v) (%v)", s.ipPort, s.query))
+	}
+	s.detail = fmt.Sprintf("value = %v", value.String)
+	if s.expectedValue != "" && value.String != s.expectedValue {
+		belog.Debug("not match value (%v) (%v <> %v)", s.ipPort, value.String, s.expectedValue)
+		return false, false, nil
+	}
+	belog.Debug("%v ok (%v)", s.driverName, s.ipPort)
+	return true, false, nil
+}
+
+func (s *sqlWatcher) isAlive() (bool) {
+	alive, err := retryProbe(s.ipPort, s.retry, s.retryWait, s.querySQL)
+	s.lastErr = err
+	return alive
+}
+
+func (s *sqlWatcher) getDetail() (string) {
+	return s.detail
+}
+
+func (s *sqlWatcher) getLastError() (error) {
+	return s.lastErr
+}
+
+const defaultSQLTimeout uint32 = 10
+
+func sqlTimeout(target *contexter.Target) (uint32) {
+	if target.Timeout == 0 {
+		return defaultSQLTimeout
+	}
+	return target.Timeout
+}
+
+var (
+	mysqlTLSConfigMutex   = new(sync.Mutex)
+	mysqlTLSConfigNameMap = make(map[string]bool)
+)
+
+// registerMySQLTLSConfig is register tls config to global registry of mysql driver once,
+// name is derived from tls options, so that targets with different options do not overwrite each other
+func registerMySQLTLSConfig(host string, option *helper.HTTPClientOption) (string, error) {
+	tlsConfigName := fmt.Sprintf("pdru-%x", sha256.Sum256([]byte(fmt.Sprintf("%v\x00%v\x00%v\x00%v\x00%v\x00%v",
+		host, option.TLSServerName, option.TLSSkipVerify, option.TLSCAFile, option.TLSClientCertFile, option.TLSClientKeyFile))))
+	mysqlTLSConfigMutex.Lock()
+	defer mysqlTLSConfigMutex.Unlock()
+	if mysqlTLSConfigNameMap[tlsConfigName] {
+		return tlsConfigName, nil
+	}
+	tlsConfig, err := helper.NewTLSConfig(host, option)
+	if err != nil {
+		return "", err
+	}
+	if err := mysql.RegisterTLSConfig(tlsConfigName, tlsConfig); err != nil {
+		return "", err
+	}
+	mysqlTLSConfigNameMap[tlsConfigName] = true
+	return tlsConfigName, nil
+}
+
+func mysqlWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
+	ipPort := target.Dest
+	host, _, err := net.SplitHostPort(ipPort)
+	if err != nil {
+		host = ipPort
+		ipPort = net.JoinHostPort(ipPort, "3306")
+	}
+	config := mysql.NewConfig()
+	config.User = target.DBUser
+	config.Passwd = target.DBPassword
+	config.Net = "tcp"
+	config.Addr = ipPort
+	config.DBName = target.DBName
+	timeout := sqlTimeout(target)
+	config.Timeout = time.Duration(timeout) * time.Second
+	config.ReadTimeout = time.Duration(timeout) * time.Second
+	config.WriteTimeout = time.Duration(timeout) * time.Second
+	config.AllowNativePasswords = true
+	if target.DBTLS {
+		tlsConfigName, err := registerMySQLTLSConfig(host, &helper.HTTPClientOption{
+			TLSSkipVerify:     target.TLSSkipVerify,
+			TLSServerName:     target.TLSServerName,
+			TLSCAFile:         target.TLSCAFile,
+			TLSClientCertFile: target.TLSClientCertFile,
+			TLSClientKeyFile:  target.TLSClientKeyFile,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("can not register tls config (%v)", ipPort))
+		}
+		config.TLSConfig = tlsConfigName
+	}
+	return &sqlWatcher {
+		driverName:    "mysql",
+		ipPort:        ipPort,
+		dataSource:    config.FormatDSN(),
+		query:         target.DBQuery,
+		expectedValue: target.DBExpectedValue,
+		retry:         target.Retry,
+		retryWait:     target.RetryWait,
+		timeout:       timeout,
+	}, nil
+}
+
+func postgresWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
+	ipPort := target.Dest
+	if _, _, err := net.SplitHostPort(ipPort); err != nil {
+		ipPort = net.JoinHostPort(ipPort, "5432")
+	}
+	sslMode := "disable"
+	if target.DBTLS {
+		if target.TLSSkipVerify {
+			sslMode = "require"
+		} else {
+			sslMode = "verify-full"
+		}
+	}
+	timeout := sqlTimeout(target)
+	query := url.Values{}
+	query.Set("sslmode", sslMode)
+	query.Set("connect_timeout", fmt.Sprintf("%v", timeout))
+	// lib/pq verifies server by host of dest, tlsServerName is rejected in validation of target
+	// lib/pq verifies ca even with sslmode=require if sslrootcert is given
+	if target.DBTLS && !target.TLSSkipVerify && target.TLSCAFile != "" {
+		query.Set("sslrootcert", target.TLSCAFile)
+	}
+	if target.DBTLS && target.TLSClientCertFile != "" {
+		query.Set("sslcert", target.TLSClientCertFile)
+		query.Set("sslkey", target.TLSClientKeyFile)
+	}
+	dataSource := &url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(target.DBUser, target.DBPassword),
+		Host:     ipPort,
+		Path:     "/" + target.DBName,
+		RawQuery: query.Encode(),
+	}
+	return &sqlWatcher {
+		driverName:    "postgres",
+		ipPort:        ipPort,
+		dataSource:    dataSource.String(),
+		query:         target.DBQuery,
+		expectedValue: target.DBExpectedValue,
+		retry:         target.Retry,
+		retryWait:     target.RetryWait,
+		timeout:       timeout,
+	}, nil
+}
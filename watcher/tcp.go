@@ -13,9 +13,17 @@ import (
 	"fmt"
 )
 
+type tcpStep struct {
+	send      []byte
+	expect    *pcre.Regexp
+	expectStr string
+	timeout   uint32
+}
+
 type tcpWatcher struct {
 	useRegexp     bool
 	ipPort        string
+	stepList      []*tcpStep
 	retry         uint32
 	retryWait     uint32
 	timeout       uint32
@@ -24,11 +32,65 @@ type tcpWatcher struct {
         resSize       uint32
 	useTLS        bool
 	tlsSkipVerify bool
+	detail        string
+	lastErr       error
 }
 
 type connIf interface {
 	Close() (error)
 	Read(b []byte) (n int, err error)
+	Write(b []byte) (n int, err error)
+	SetDeadline(t time.Time) (error)
+}
+
+const defaultTCPStepTimeout uint32 = 10
+
+func (t *tcpWatcher) doStep(conn connIf, index int, step *tcpStep) (bool, bool, error) {
+	timeout := step.timeout
+	if timeout == 0 {
+		timeout = t.timeout
+	}
+	if timeout == 0 {
+		// deadline of now makes every read and write fail
+		timeout = defaultTCPStepTimeout
+	}
+	if err := conn.SetDeadline(time.Now().Add(time.Duration(timeout) * time.Second)); err != nil {
+		return false, false, errors.Wrap(err, fmt.Sprintf("can not set deadline (%v)", t.ipPort))
+	}
+	if len(step.send) > 0 {
+		if _, err := conn.Write(step.send); err != nil {
+			t.detail = fmt.Sprintf("step %v: can not write (%v)", index, err)
+			return false, true, errors.Wrap(err, fmt.Sprintf("can not write step %v (%v)", index, t.ipPort))
+		}
+	}
+	if step.expect == nil {
+		return true, false, nil
+	}
+	rb := make([]byte, 0, t.resSize)
+	buf := make([]byte, t.resSize)
+	for {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			rb = append(rb, buf[:n]...)
+			if step.expect.FindIndex(rb, 0) != nil {
+				return true, false, nil
+			}
+			if uint32(len(rb)) >= t.resSize {
+				belog.Debug("not match expect (%v) (%v)", step.expectStr, rb)
+				t.detail = fmt.Sprintf("step %v: not match expect (%v)", index, step.expectStr)
+				return false, false, nil
+			}
+		}
+		if err != nil {
+			if len(rb) > 0 {
+				belog.Debug("not match expect (%v) (%v)", step.expectStr, rb)
+				t.detail = fmt.Sprintf("step %v: not match expect (%v)", index, step.expectStr)
+				return false, false, nil
+			}
+			t.detail = fmt.Sprintf("step %v: can not read (%v)", index, err)
+			return false, true, errors.Wrap(err, fmt.Sprintf("can not read step %v response (%v)", index, t.ipPort))
+		}
+	}
 }
 
 func (t *tcpWatcher) connectTCP() (bool, bool, error) {
@@ -38,6 +100,7 @@ func (t *tcpWatcher) connectTCP() (bool, bool, error) {
 		Deadline:  time.Now().Add(time.Duration(t.timeout) * time.Second),
 	}
 
+	t.detail = ""
 	var conn connIf
 	var err error
 	if t.useTLS {
@@ -53,10 +116,10 @@ func (t *tcpWatcher) connectTCP() (bool, bool, error) {
 	}
 	defer conn.Close()
 
+	if t.resSize == 0 {
+		t.resSize = 1024
+	}
 	if (t.useRegexp) {
-		if t.resSize == 0 {
-			t.resSize = 1024
-		}
 		rb := make([]byte, t.resSize)
 		_, err := conn.Read(rb)
 		if err != nil {
@@ -65,38 +128,74 @@ func (t *tcpWatcher) connectTCP() (bool, bool, error) {
 		loc := t.regexp.FindIndex(rb, 0)
 		if loc == nil {
 			belog.Debug("not match regexp (%v) (%v)", t.regexpStr, rb)
+			t.detail = fmt.Sprintf("not match regexp (%v)", t.regexpStr)
 			return false, false, nil
 		}
 	}
+	for i, step := range t.stepList {
+		alive, retryable, err := t.doStep(conn, i, step)
+		if !alive {
+			return alive, retryable, err
+		}
+	}
 	belog.Debug("tcp ok (%v)", t.ipPort)
 	return true, false, nil
 }
 
 func (t *tcpWatcher) isAlive() (bool) {
-	var i uint32
-        for i = 0; i <= t.retry; i++ {
-                alive, retryable, err := t.connectTCP()
-                if err != nil {
-                        belog.Error("%v", err)
-                }
-                if !retryable {
-                        return alive
-                }
-                if t.retryWait > 0 {
-                        time.Sleep(time.Duration(t.retryWait) * time.Second)
-                }
-        }
-        belog.Error("retry count is exceeded limit (%v)", t.ipPort)
-        return false
+	alive, err := retryProbe(t.ipPort, t.retry, t.retryWait, t.connectTCP)
+	t.lastErr = err
+	return alive
+}
+
+func (t *tcpWatcher) getDetail() (string) {
+	return t.detail
+}
+
+func (t *tcpWatcher) getLastError() (error) {
+	return t.lastErr
+}
+
+func newTCPStepList(target *contexter.Target) ([]*tcpStep, error) {
+	if target.TCPStepList == nil {
+		return nil, nil
+	}
+	stepList := make([]*tcpStep, 0, len(target.TCPStepList))
+	for _, targetStep := range target.TCPStepList {
+		send, err := decodePayload(targetStep.Send, targetStep.SendHex)
+		if err != nil {
+			return nil, err
+		}
+		newTCPStep := &tcpStep {
+			send:      send,
+			expectStr: targetStep.Expect,
+			timeout:   targetStep.Timeout,
+		}
+		if targetStep.Expect != "" {
+			regexp, err := cacher.GetRegexpFromCache(targetStep.Expect, 0)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("can not get compiled regexp (%v)", targetStep.Expect))
+			}
+			newTCPStep.expect = regexp
+		}
+		stepList = append(stepList, newTCPStep)
+	}
+	return stepList, nil
 }
 
 func tcpWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
+	stepList, err := newTCPStepList(target)
+	if err != nil {
+		return nil, err
+	}
         return &tcpWatcher {
 		useRegexp:     false,
                 ipPort:        target.Dest,
+		stepList:      stepList,
                 retry:         target.Retry,
                 retryWait:     target.RetryWait,
                 timeout:       target.Timeout,
+		resSize:       target.ResSize,
 		useTLS:        target.TCPTLS,
 		tlsSkipVerify: target.TLSSkipVerify,
         }, nil
@@ -107,9 +206,14 @@ func tcpRegexpWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("can not get compiled regexp (%v)", target.Regexp))
 	}
+	stepList, err := newTCPStepList(target)
+	if err != nil {
+		return nil, err
+	}
         return &tcpWatcher {
 		useRegexp: true,
                 ipPort:        target.Dest,
+		stepList:      stepList,
                 retry:         target.Retry,
                 retryWait:     target.RetryWait,
                 timeout:       target.Timeout,
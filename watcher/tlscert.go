@@ -0,0 +1,135 @@
+package watcher
+
+import (
+	"github.com/pkg/errors"
+	"github.com/potix/belog"
+	"github.com/potix/pdns-record-updater/contexter"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"time"
+	"fmt"
+)
+
+type tlsCertWatcher struct {
+	ipPort        string
+	serverName    string
+	expireDays    uint32
+	rootCAs       *x509.CertPool
+	retry         uint32
+	retryWait     uint32
+	timeout       uint32
+	tlsSkipVerify bool
+	detail        string
+	lastErr       error
+}
+
+func (t *tlsCertWatcher) checkCert() (bool, bool, error) {
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(t.timeout) * time.Second,
+		DualStack: true,
+		Deadline:  time.Now().Add(time.Duration(t.timeout) * time.Second),
+	}
+	belog.Debug("tls cert (%v) (%v)", t.ipPort, t.serverName)
+	// verify by myself after handshake
+	tlsConfig := &tls.Config{ ServerName: t.serverName, InsecureSkipVerify: true }
+	conn, err := tls.DialWithDialer(dialer, "tcp", t.ipPort, tlsConfig)
+	if err != nil {
+		t.detail = fmt.Sprintf("handshake failed: %v", err)
+		return false, true, errors.Wrap(err, fmt.Sprintf("can not connect (%v)", t.ipPort))
+	}
+	defer conn.Close()
+	peerCertificates := conn.ConnectionState().PeerCertificates
+	if len(peerCertificates) == 0 {
+		t.detail = "no peer certificate"
+		return false, false, errors.Errorf("no peer certificate (%v)", t.ipPort)
+	}
+	leaf := peerCertificates[0]
+	t.detail = fmt.Sprintf("notAfter = %v", leaf.NotAfter.Format("2006-01-02 15:04:05 MST"))
+	now := time.Now()
+	if now.After(leaf.NotAfter) {
+		belog.Debug("certificate is already expired (%v) (%v)", t.ipPort, leaf.NotAfter)
+		t.detail = t.detail + ", expired"
+		return false, false, nil
+	}
+	if now.Add(time.Duration(t.expireDays) * 24 * time.Hour).After(leaf.NotAfter) {
+		belog.Debug("certificate expires within %v days (%v) (%v)", t.expireDays, t.ipPort, leaf.NotAfter)
+		t.detail = t.detail + fmt.Sprintf(", expires within %v days", t.expireDays)
+		return false, false, nil
+	}
+	if t.serverName != "" {
+		if err := leaf.VerifyHostname(t.serverName); err != nil {
+			belog.Debug("certificate does not cover server name (%v) (%v)", t.ipPort, t.serverName)
+			t.detail = t.detail + fmt.Sprintf(", not cover %v", t.serverName)
+			return false, false, nil
+		}
+	}
+	if !t.tlsSkipVerify {
+		intermediates := x509.NewCertPool()
+		for _, cert := range peerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		verifyOptions := x509.VerifyOptions{
+			DNSName:       t.serverName,
+			Roots:         t.rootCAs,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+		}
+		if _, err := leaf.Verify(verifyOptions); err != nil {
+			belog.Debug("can not verify certificate chain (%v) (%v)", t.ipPort, err)
+			t.detail = t.detail + fmt.Sprintf(", chain verify failed: %v", err)
+			return false, false, nil
+		}
+	}
+	belog.Debug("tls cert ok (%v)", t.ipPort)
+	return true, false, nil
+}
+
+func (t *tlsCertWatcher) isAlive() (bool) {
+	alive, err := retryProbe(t.ipPort, t.retry, t.retryWait, t.checkCert)
+	t.lastErr = err
+	return alive
+}
+
+func (t *tlsCertWatcher) getDetail() (string) {
+	return t.detail
+}
+
+func (t *tlsCertWatcher) getLastError() (error) {
+	return t.lastErr
+}
+
+func tlsCertWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
+	ipPort := target.Dest
+	host, _, err := net.SplitHostPort(ipPort)
+	if err != nil {
+		host = ipPort
+		ipPort = net.JoinHostPort(ipPort, "443")
+	}
+	serverName := target.TLSServerName
+	if serverName == "" && net.ParseIP(host) == nil {
+		serverName = host
+	}
+	var rootCAs *x509.CertPool
+	if target.TLSCAFile != "" {
+		pem, err := ioutil.ReadFile(target.TLSCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("can not read ca file (%v)", target.TLSCAFile))
+		}
+		rootCAs = x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("can not append ca certificates (%v)", target.TLSCAFile)
+		}
+	}
+	return &tlsCertWatcher {
+		ipPort:        ipPort,
+		serverName:    serverName,
+		expireDays:    target.TLSCertExpireDays,
+		rootCAs:       rootCAs,
+		retry:         target.Retry,
+		retryWait:     target.RetryWait,
+		timeout:       target.Timeout,
+		tlsSkipVerify: target.TLSSkipVerify,
+	}, nil
+}
@@ -0,0 +1,139 @@
+package watcher
+
+import (
+	"github.com/pkg/errors"
+	"github.com/potix/belog"
+	"github.com/glenn-brown/golang-pkg-pcre/src/pkg/pcre"
+	"github.com/potix/pdns-record-updater/contexter"
+	"github.com/potix/pdns-record-updater/cacher"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"net"
+	"time"
+	"fmt"
+)
+
+type udpWatcher struct {
+	useRegexp bool
+	ipPort    string
+	payload   []byte
+	retry     uint32
+	retryWait uint32
+	timeout   uint32
+	regexp    *pcre.Regexp
+	regexpStr string
+	resSize   uint32
+	detail    string
+	lastErr   error
+}
+
+func decodePayload(payload string, payloadHex bool) ([]byte, error) {
+	if payloadHex {
+		b, err := hex.DecodeString(strings.Replace(payload, " ", "", -1))
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("can not decode hex payload (%v)", payload))
+		}
+		return b, nil
+	}
+	s, err := strconv.Unquote("\"" + strings.Replace(payload, "\"", "\\\"", -1) + "\"")
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("can not unescape payload (%v)", payload))
+	}
+	return []byte(s), nil
+}
+
+func (u *udpWatcher) sendUDP() (bool, bool, error) {
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(u.timeout) * time.Second,
+		DualStack: true,
+		Deadline:  time.Now().Add(time.Duration(u.timeout) * time.Second),
+	}
+	u.detail = ""
+	belog.Debug("udp (%v)", u.ipPort)
+	conn, err := dialer.Dial("udp", u.ipPort)
+	if err != nil {
+		u.detail = fmt.Sprintf("can not connect (%v)", err)
+		return false, true, errors.Wrap(err, fmt.Sprintf("can not connect (%v)", u.ipPort))
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(time.Duration(u.timeout) * time.Second)); err != nil {
+		u.detail = fmt.Sprintf("can not set deadline (%v)", err)
+		return false, false, errors.Wrap(err, fmt.Sprintf("can not set deadline (%v)", u.ipPort))
+	}
+	if _, err := conn.Write(u.payload); err != nil {
+		u.detail = fmt.Sprintf("can not write payload (%v)", err)
+		return false, true, errors.Wrap(err, fmt.Sprintf("can not write payload (%v)", u.ipPort))
+	}
+	if u.resSize == 0 {
+		u.resSize = 1024
+	}
+	rb := make([]byte, u.resSize)
+	rlen, err := conn.Read(rb)
+	if err != nil {
+		u.detail = fmt.Sprintf("can not read response (%v)", err)
+		return false, true, errors.Wrap(err, fmt.Sprintf("can not read response (%v)", u.ipPort))
+	}
+	if u.useRegexp {
+		loc := u.regexp.FindIndex(rb[:rlen], 0)
+		if loc == nil {
+			belog.Debug("not match regexp (%v) (%v)", u.regexpStr, rb[:rlen])
+			u.detail = fmt.Sprintf("not match regexp (%v)", u.regexpStr)
+			return false, false, nil
+		}
+	}
+	belog.Debug("udp ok (%v)", u.ipPort)
+	return true, false, nil
+}
+
+func (u *udpWatcher) isAlive() (bool) {
+	alive, err := retryProbe(u.ipPort, u.retry, u.retryWait, u.sendUDP)
+	u.lastErr = err
+	return alive
+}
+
+func (u *udpWatcher) getDetail() (string) {
+	return u.detail
+}
+
+func (u *udpWatcher) getLastError() (error) {
+	return u.lastErr
+}
+
+func udpWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
+	payload, err := decodePayload(target.Payload, target.PayloadHex)
+	if err != nil {
+		return nil, err
+	}
+	return &udpWatcher {
+		useRegexp: false,
+		ipPort:    target.Dest,
+		payload:   payload,
+		retry:     target.Retry,
+		retryWait: target.RetryWait,
+		timeout:   target.Timeout,
+		resSize:   target.ResSize,
+	}, nil
+}
+
+func udpRegexpWatcherNew(target *contexter.Target) (protoWatcherIf, error) {
+	payload, err := decodePayload(target.Payload, target.PayloadHex)
+	if err != nil {
+		return nil, err
+	}
+	regexp, err := cacher.GetRegexpFromCache(target.Regexp, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("can not get compiled regexp (%v)", target.Regexp))
+	}
+	return &udpWatcher {
+		useRegexp: true,
+		ipPort:    target.Dest,
+		payload:   payload,
+		retry:     target.Retry,
+		retryWait: target.RetryWait,
+		timeout:   target.Timeout,
+		regexp:    regexp,
+		regexpStr: target.Regexp,
+		resSize:   target.ResSize,
+	}, nil
+}
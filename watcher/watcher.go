@@ -5,10 +5,11 @@ import (
         "github.com/potix/belog"
 	"github.com/potix/pdns-record-updater/contexter"
 	"github.com/potix/pdns-record-updater/notifier"
-        "go/token"
-        "go/types"
-        "go/constant"
+	"github.com/potix/pdns-record-updater/persister"
+	"github.com/potix/pdns-record-updater/metrics"
 	"sync/atomic"
+	"sync"
+	"sort"
         "strings"
         "time"
         "os"
@@ -27,6 +28,10 @@ type Watcher struct {
 	context         *contexter.Context
 	running         uint32
 	notifier	*notifier.Notifier
+	persister       *persister.Persister
+	scheduler       *scheduler
+	updateChan      chan bool
+	recordLabelMap  map[string][]string
 }
 
 type targetTask struct {
@@ -39,18 +44,50 @@ type protoWatcherIf interface {
 	isAlive() (bool)
 }
 
+type protoWatcherDetailIf interface {
+	getDetail() (string)
+}
+
+type protoWatcherErrorIf interface {
+	getLastError() (error)
+}
+
+type protoWatcherMetricIf interface {
+	getMetric() (*contexter.TargetMetric)
+}
+
 var protoWatcherNewFuncMap = map[string]func(*contexter.Target) (protoWatcherIf, error) {
 	"ICMP":       icmpWatcherNew,
-//not implemented "UDP":        udpWatcherNew, 
-//not implemented "UDPREGEXP":   udpRegexpWatcherNew,
+	"UDP":        udpWatcherNew,
+	"UDPREGEXP":  udpRegexpWatcherNew,
 	"TCP":        tcpWatcherNew,
 	"TCPREGEXP":  tcpRegexpWatcherNew,
 	"HTTP":       httpWatcherNew,
 	"HTTPREGEXP": httpRegexpWatcherNew,
+	"HTTPJSON":   httpJSONWatcherNew,
+	"DNS":        dnsWatcherNew,
+	"TLSCERT":    tlsCertWatcherNew,
+	"EXEC":       execWatcherNew,
+	"GRPC":       grpcWatcherNew,
+	"MYSQL":      mysqlWatcherNew,
+	"POSTGRES":   postgresWatcherNew,
 }
 
-func (w *Watcher) eval(expr string) (types.TypeAndValue, error) {
-	return types.Eval(token.NewFileSet(), nil, token.NoPos, expr)
+func (w Watcher) newNotifyVarList(domain string, groupName string, record *contexter.DynamicRecord, targetResult string, newAlive bool, oldAlive bool, flapping bool) ([]string) {
+	t := time.Now()
+        return []string{
+                "%(hostname)", w.hostname,
+                "%(time)", t.Format("2006-01-02 15:04:05"),
+                "%(domain)", domain,
+                "%(groupName)", groupName,
+                "%(name)", record.Name,
+                "%(type)", record.Type,
+                "%(content)", record.Content,
+                "%(oldAlive)", fmt.Sprintf("%v", oldAlive),
+                "%(newAlive)", fmt.Sprintf("%v", newAlive),
+                "%(flapping)", fmt.Sprintf("%v", flapping),
+                "%(detail)", targetResult,
+	}
 }
 
 func (w Watcher) notify(watcherContext *contexter.Watcher, domain string, groupName string, record *contexter.DynamicRecord, targetResult string, newAlive bool, oldAlive bool) {
@@ -64,18 +101,7 @@ func (w Watcher) notify(watcherContext *contexter.Watcher, domain string, groupN
 			triggerFlags |= tfLatestUp
 		}
 	}
-	t := time.Now()
-        replacer := strings.NewReplacer(
-                "%(hostname)", w.hostname,
-                "%(time)", t.Format("2006-01-02 15:04:05"),
-                "%(domain)", domain,
-                "%(groupName)", groupName,
-                "%(name)", record.Name,
-                "%(type)", record.Type,
-                "%(content)", record.Content,
-                "%(oldAlive)", fmt.Sprintf("%v", oldAlive),
-                "%(newAlive)", fmt.Sprintf("%v", newAlive),
-                "%(detail)", targetResult)
+	varList := w.newNotifyVarList(domain, groupName, record, targetResult, newAlive, oldAlive, false)
 	subject := watcherContext.NotifySubject
 	if subject == "" {
 		subject = "%(hostname) %(domain) %(groupName) %(name) %(content): old alive = %(oldAlive) -> new alive = %(newAlive)"
@@ -86,56 +112,145 @@ func (w Watcher) notify(watcherContext *contexter.Watcher, domain string, groupN
 	}
 	if (triggerFlags & tfChanged) != 0 && oldAlive != newAlive {
 		belog.Debug("notify changed")
-		w.notifier.Notify(replacer, subject, body)
+		w.notifier.Notify(varList, subject, body)
 	} else if (triggerFlags & tfLatestDown) != 0 && !newAlive {
 		belog.Debug("notify latestdown")
-		w.notifier.Notify(replacer, subject, body)
+		w.notifier.Notify(varList, subject, body)
 	} else if (triggerFlags & tfLatestUp) != 0 && newAlive {
 		belog.Debug("notify latestup")
-		w.notifier.Notify(replacer, subject, body)
+		w.notifier.Notify(varList, subject, body)
 	}
 }
 
+func (w Watcher) notifyFlapping(domain string, groupName string, record *contexter.DynamicRecord, targetResult string, newAlive bool, oldAlive bool, flapping bool) {
+	varList := w.newNotifyVarList(domain, groupName, record, targetResult, newAlive, oldAlive, flapping)
+	state := "stopped"
+	if flapping {
+		state = "started"
+	}
+	subject := "%(hostname) %(domain) %(groupName) %(name) %(content): flapping " + state
+	body := "hostname: %(hostname)\ndomain: %(domain)\ngroupName: %(groupName)\nrecord: %(name) %(type) %(content)\n%(time) flapping " + state + ", old alive = %(oldAlive) -> new alive = %(newAlive)\n\n-----\n%(detail)\n"
+	belog.Debug("notify flapping %v", state)
+	w.notifier.Notify(varList, subject, body)
+}
+
 func (w *Watcher) updateAlive(watcherContext *contexter.Watcher, domain string, groupName string, record *contexter.DynamicRecord, targetResult string, newAlive bool){
+	flapping, flapChanged := record.UpdateFlapState(newAlive, time.Now())
+	if flapping {
+		// hold down while damped
+		newAlive = false
+	}
 	oldAlive := record.SwapAlive(newAlive);
-	belog.Debug("%v %v %v: new alive = %v, old alive = %v", record.Name, record.Type, record.Content, newAlive, oldAlive)
+	if oldAlive != newAlive {
+		record.AddAliveTransition(&contexter.AliveTransition{
+			Time:     time.Now(),
+			OldAlive: oldAlive,
+			NewAlive: newAlive,
+			Flapping: flapping,
+		})
+	}
+	metrics.SetDynamicRecordAlive(domain, groupName, record.Name, record.Type, record.Content, newAlive)
+	belog.Debug("%v %v %v: new alive = %v, old alive = %v, flapping = %v", record.Name, record.Type, record.Content, newAlive, oldAlive, flapping)
+	if record.InMaintenance() {
+		// suppress alert during maintenance
+		belog.Debug("%v %v %v: in maintenance %v, skip notify", record.Name, record.Type, record.Content, record.GetMaintenanceNameList())
+		return
+	}
+	if !newAlive {
+		if unreachableTargetNameList, allUnreachable := w.unreachableTargetNameList(watcherContext, record); allUnreachable {
+			// suppress alert in favour of root cause notification, only when no target of record is down by itself
+			record.SwapSuppressedDown(true)
+			belog.Debug("%v %v %v: unreachable targets %v, skip notify", record.Name, record.Type, record.Content, unreachableTargetNameList)
+			return
+		}
+	}
+	if suppressedDown := record.SwapSuppressedDown(false); suppressedDown && newAlive {
+		// recovery is also covered by root cause notification
+		belog.Debug("%v %v %v: recovered from unreachable, skip notify", record.Name, record.Type, record.Content)
+		return
+	}
 	if record.NotifyTriggerList != nil {
-		w.notify(watcherContext, domain, groupName, record, targetResult, newAlive, oldAlive)
+		if flapChanged {
+			// notify only once on flapping started or stopped instead of changed
+			w.notifyFlapping(domain, groupName, record, targetResult, newAlive, oldAlive, flapping)
+		} else {
+			w.notify(watcherContext, domain, groupName, record, targetResult, newAlive, oldAlive)
+		}
 	}
 }
 
 func (w *Watcher) updateRecord(watcherContext *contexter.Watcher ,domain string, groupName string, record *contexter.DynamicRecord) {
-	// create replacer
-	replaceNameList := make([]string, 0, 2 * len(record.TargetNameList))
 	targetResult := ""
 	for _, targetName := range record.TargetNameList {
 		target, err := watcherContext.GetTarget(targetName)
 		if err != nil {
 			belog.Warn("%v", errors.Wrap(err, fmt.Sprintf("not found target (%v)", targetName)))
-			replaceNameList = append(replaceNameList, fmt.Sprintf("%%(%v)", targetName), "false")
 			targetResult = targetResult + fmt.Sprintf("%v %v %v %v %v %v %v %v\n",
 				domain, groupName, record.Name, record.Type, record.Content, targetName, "(no dest)", "false")
 			continue
 		}
-		replaceNameList = append(replaceNameList, fmt.Sprintf("%%(%v)", targetName), fmt.Sprintf("%v", target.GetAlive()))
-		targetResult = targetResult + fmt.Sprintf("%v %v %v %v %v %v %v %v\n",
+		targetResult = targetResult + fmt.Sprintf("%v %v %v %v %v %v %v %v",
 			domain, groupName, record.Name, record.Type, record.Content, targetName, target.Dest, target.GetAlive())
+		if detail := target.GetDetail(); detail != "" {
+			targetResult = targetResult + fmt.Sprintf(" (%v)", detail)
+		}
+		targetResult = targetResult + "\n"
 
 	}
-        replacer := strings.NewReplacer(replaceNameList...)
 	// exec eval
-	evalString := replacer.Replace(record.EvalRule)
-	belog.Debug("%v %v %v: eval = %v", record.Name, record.Type, record.Content, evalString)
-	tv, err := w.eval(evalString)
+	evalRule, err := record.GetEvalRule()
+	if err != nil {
+		belog.Error("%v", err)
+		w.updateAlive(watcherContext, domain, groupName, record, targetResult, false)
+		return
+	}
+	alive, err := evalRule.Eval(&ruleEnv{ watcherContext: watcherContext })
 	if err != nil {
-		belog.Error("can not evalute (%v)", replacer.Replace(record.EvalRule))
+		belog.Error("%v", err)
 		w.updateAlive(watcherContext, domain, groupName, record, targetResult, false)
-	} else {
-		w.updateAlive(watcherContext, domain, groupName, record, targetResult, constant.BoolVal(tv.Value))
+		return
+	}
+	belog.Debug("%v %v %v: eval (%v) = %v", record.Name, record.Type, record.Content, evalRule, alive)
+	w.updateAlive(watcherContext, domain, groupName, record, targetResult, alive)
+}
+
+func (w *Watcher) activeMaintenanceMap(watcherContext *contexter.Watcher, now time.Time) (map[string]*contexter.Maintenance) {
+	activeMaintenanceMap := make(map[string]*contexter.Maintenance)
+	for _, maintenanceName := range watcherContext.GetMaintenanceNameList() {
+		maintenance, err := watcherContext.GetMaintenance(maintenanceName)
+		if err != nil {
+			continue
+		}
+		active, changed := maintenance.UpdateActive(now)
+		if changed {
+			if active {
+				belog.Info("maintenance (%v) started: %v", maintenanceName, maintenance)
+			} else {
+				belog.Info("maintenance (%v) ended: %v", maintenanceName, maintenance)
+			}
+		}
+		if active {
+			activeMaintenanceMap[maintenanceName] = maintenance
+		}
 	}
+	return activeMaintenanceMap
+}
+
+func (w *Watcher) updateMaintenance(activeMaintenanceMap map[string]*contexter.Maintenance, domain string, groupName string, record *contexter.DynamicRecord) {
+	var maintenanceNameList []string
+	for maintenanceName, maintenance := range activeMaintenanceMap {
+		if maintenance.Match(domain, groupName, record) {
+			maintenanceNameList = append(maintenanceNameList, maintenanceName)
+		}
+	}
+	sort.Strings(maintenanceNameList)
+	record.SetMaintenanceNameList(maintenanceNameList)
 }
 
 func (w *Watcher) update(watcherContext *contexter.Watcher) {
+	activeMaintenanceMap := w.activeMaintenanceMap(watcherContext, time.Now())
+	w.updateDependency(watcherContext, activeMaintenanceMap)
+	recordLabelMap := make(map[string][]string)
         domainList := watcherContext.GetDomainList()
         for _, domain := range domainList {
                 zone, err := watcherContext.GetZone(domain)
@@ -151,81 +266,276 @@ func (w *Watcher) update(watcherContext *contexter.Watcher) {
                                 continue
                         }
                         for _, record := range dynamicGroup.GetDynamicRecordList() {
+                                w.updateMaintenance(activeMaintenanceMap, domain, dynamicGroupName, record)
                                 w.updateRecord(watcherContext, domain, dynamicGroupName, record)
+                                recordLabelMap[persister.DynamicRecordKey(domain, dynamicGroupName, record)] = []string{
+                                        domain, dynamicGroupName, record.Name, record.Type, record.Content }
                         }
                 }
         }
+	for key, recordLabel := range w.recordLabelMap {
+		if _, ok := recordLabelMap[key]; !ok {
+			// deleted record
+			metrics.DeleteDynamicRecordAlive(recordLabel[0], recordLabel[1], recordLabel[2], recordLabel[3], recordLabel[4])
+		}
+	}
+	w.recordLabelMap = recordLabelMap
 }
 
-func (w *Watcher) targetWatch(target *contexter.Target) {
+// retryProbe is run probe until it finishes without retryable error or retry count is exceeded,
+// return alive and error of last attempt
+func retryProbe(name string, retry uint32, retryWait uint32, probe func() (bool, bool, error)) (bool, error) {
+	var lastErr error
+	var i uint32
+	for i = 0; i <= retry; i++ {
+		alive, retryable, err := probe()
+		lastErr = err
+		if err != nil {
+			belog.Error("%v", err)
+		}
+		if !retryable {
+			return alive, lastErr
+		}
+		if retryWait > 0 {
+			time.Sleep(time.Duration(retryWait) * time.Second)
+		}
+	}
+	belog.Error("retry count is exceeded limit (%v)", name)
+	return false, lastErr
+}
+
+// updateTargetAlive is update alive of target by probe result with rise and fall count, except on initial run
+func (w *Watcher) updateTargetAlive(target *contexter.Target, probeAlive bool, initial bool) {
+	if initial {
+		// no previous state, so use first probe result as it is
+		target.SetAlive(probeAlive)
+		return
+	}
+	alive := target.UpdateAlive(probeAlive)
+	belog.Debug("%v: probe alive = %v, alive = %v (rise %v/%v, fall %v/%v)", target.Dest, probeAlive, alive,
+		target.GetRiseCounter(), target.RiseCount, target.GetFallCounter(), target.FallCount)
+}
+
+func (w *Watcher) targetWatch(targetName string, target *contexter.Target, initial bool) {
 	protoWatcherNewFunc, ok := protoWatcherNewFuncMap[strings.ToUpper(target.Protocol)]
 	if !ok {
 		belog.Error("unsupported protocol type (%v)", target.Protocol)
-		target.SetAlive(false)
+		w.updateTargetAlive(target, false, initial)
+		target.AddProbeResult(&contexter.ProbeResult{ Time: time.Now(), Error: fmt.Sprintf("unsupported protocol type (%v)", target.Protocol) })
+		metrics.ObserveProbe(targetName, strings.ToLower(target.Protocol), 0, false)
 		return
 	}
 	protoWatcher, err := protoWatcherNewFunc(target)
 	if err != nil {
-		belog.Error("%v", errors.Wrap(err, fmt.Sprintf("can not create protocol watcher (%v)", target.Protocol)))
-		target.SetAlive(false)
+		err = errors.Wrap(err, fmt.Sprintf("can not create protocol watcher (%v)", target.Protocol))
+		belog.Error("%v", err)
+		w.updateTargetAlive(target, false, initial)
+		target.AddProbeResult(&contexter.ProbeResult{ Time: time.Now(), Error: err.Error() })
+		metrics.ObserveProbe(targetName, strings.ToLower(target.Protocol), 0, false)
 		return
 	}
-	target.SetAlive(protoWatcher.isAlive())
+	start := time.Now()
+	target.SetLastProbeTime(start)
+	probeAlive := protoWatcher.isAlive()
+	duration := time.Since(start)
+	latency := float64(duration) / float64(time.Millisecond)
+	metrics.ObserveProbe(targetName, strings.ToLower(target.Protocol), duration, probeAlive)
+	w.updateTargetAlive(target, probeAlive, initial)
+	detail := ""
+	if protoWatcherDetail, ok := protoWatcher.(protoWatcherDetailIf); ok {
+		detail = protoWatcherDetail.getDetail()
+		target.SetDetail(detail)
+	}
+	probeResult := &contexter.ProbeResult{
+		Time:     start,
+		Duration: latency,
+		Success:  probeAlive,
+	}
+	if !probeAlive {
+		probeResult.Error = detail
+		if probeResult.Error == "" {
+			if protoWatcherError, ok := protoWatcher.(protoWatcherErrorIf); ok && protoWatcherError.getLastError() != nil {
+				probeResult.Error = protoWatcherError.getLastError().Error()
+			}
+		}
+		if probeResult.Error == "" {
+			probeResult.Error = "not alive"
+		}
+	}
+	target.AddProbeResult(probeResult)
+	metric := &contexter.TargetMetric{ Latency: latency }
+	if protoWatcherMetric, ok := protoWatcher.(protoWatcherMetricIf); ok {
+		if m := protoWatcherMetric.getMetric(); m != nil {
+			metric = m
+		}
+	}
+	target.SetMetric(metric)
 	target.SetProgress(false)
 }
 
-func (w *Watcher) watchLoop() {
-	for atomic.LoadUint32(&w.running) == 1 {
-		watcherContext := w.context.GetWatcher()
-		targetNameList := watcherContext.GetTargetNameList()
-		for _, targetName := range targetNameList {
-			target, err := watcherContext.GetTarget(targetName)
+func (w *Watcher) saveState(watcherContext *contexter.Watcher) {
+	if w.persister == nil {
+		return
+	}
+	state := persister.NewState()
+	for _, targetName := range watcherContext.GetTargetNameList() {
+		target, err := watcherContext.GetTarget(targetName)
+		if err != nil {
+			continue
+		}
+		state.TargetMap[targetName] = target.GetState()
+	}
+	for _, domain := range watcherContext.GetDomainList() {
+		zone, err := watcherContext.GetZone(domain)
+		if err != nil {
+			continue
+		}
+		for _, dynamicGroupName := range zone.GetDynamicGroupNameList() {
+			dynamicGroup, err := zone.GetDynamicGroup(dynamicGroupName)
 			if err != nil {
-				belog.Notice("%v", err)
 				continue
 			}
-			if (target.GetCurrentIntervalCount() >= target.WatchInterval) {
-				if (target.CompareAndSwapProgress(false, true)) {
-					// run record waatch task
-					go w.targetWatch(target)
-					target.ClearCurrentIntervalCount()
-					continue
-				} else {
-					// already progress last record watch task
+			for _, record := range dynamicGroup.GetDynamicRecordList() {
+				state.DynamicRecordMap[persister.DynamicRecordKey(domain, dynamicGroupName, record)] = record.GetState()
+			}
+		}
+	}
+	if err := w.persister.Save(state); err != nil {
+		belog.Error("%v", err)
+	}
+}
+
+func isRestoredRecord(record *contexter.DynamicRecord, restoredTargetMap map[string]bool) (bool) {
+	for _, targetName := range record.TargetNameList {
+		if !restoredTargetMap[targetName] {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *Watcher) restoreState(watcherContext *contexter.Watcher) (map[string]bool) {
+	restoredTargetMap := make(map[string]bool)
+	if w.persister == nil {
+		return restoredTargetMap
+	}
+	state, err := w.persister.Load()
+	if err != nil {
+		belog.Error("%v", err)
+		return restoredTargetMap
+	}
+	if state == nil {
+		return restoredTargetMap
+	}
+	now := time.Now()
+	for _, targetName := range watcherContext.GetTargetNameList() {
+		target, err := watcherContext.GetTarget(targetName)
+		if err != nil {
+			continue
+		}
+		targetState := state.GetFreshTargetState(targetName, target.GetInterval(), now)
+		if targetState == nil {
+			continue
+		}
+		target.SetState(targetState)
+		restoredTargetMap[targetName] = true
+	}
+	for _, domain := range watcherContext.GetDomainList() {
+		zone, err := watcherContext.GetZone(domain)
+		if err != nil {
+			continue
+		}
+		for _, dynamicGroupName := range zone.GetDynamicGroupNameList() {
+			dynamicGroup, err := zone.GetDynamicGroup(dynamicGroupName)
+			if err != nil {
+				continue
+			}
+			for _, record := range dynamicGroup.GetDynamicRecordList() {
+				if !isRestoredRecord(record, restoredTargetMap) {
+					// state of record depends on state of targets
 					continue
 				}
+				if recordState, ok := state.DynamicRecordMap[persister.DynamicRecordKey(domain, dynamicGroupName, record)]; ok {
+					record.SetState(recordState)
+				}
 			}
-			target.IncrementCurrentIntervalCount()
 		}
+	}
+	belog.Info("restored state (saved at %v)", state.SavedAt)
+	return restoredTargetMap
+}
+
+// requestUpdate is request evaluation of records, requests while update is pending are coalesced
+func (w *Watcher) requestUpdate() {
+	select {
+	case w.updateChan <- true:
+	default:
+	}
+}
+
+func (w *Watcher) watchLoop() {
+	lastSaveTime := time.Now()
+	for atomic.LoadUint32(&w.running) == 1 {
+		watcherContext := w.context.GetWatcher()
 		w.update(watcherContext)
-		time.Sleep(time.Second)
+		saveInterval := watcherContext.StateSaveInterval
+		if saveInterval == 0 {
+			saveInterval = 10
+		}
+		if time.Since(lastSaveTime) >= time.Duration(saveInterval) * time.Second {
+			w.saveState(watcherContext)
+			lastSaveTime = time.Now()
+		}
+		// evaluate records as soon as probe finished, sub-second intervals are not delayed
+		select {
+		case <-w.updateChan:
+		case <-time.After(time.Second):
+		}
 	}
 }
 
 // Init is Init
 func (w *Watcher) Init() {
 	watcherContext := w.context.GetWatcher()
+	restoredTargetMap := w.restoreState(watcherContext)
+	// probe with bounded concurrency
+	semaphore := make(chan bool, w.scheduler.workerCount)
+	waitGroup := new(sync.WaitGroup)
 	targetNameList := watcherContext.GetTargetNameList()
 	for _, targetName := range targetNameList {
+		if restoredTargetMap[targetName] {
+			// resume from restored state
+			continue
+		}
 		target, err := watcherContext.GetTarget(targetName)
 		if err != nil {
 			belog.Notice("%v", err)
 			continue
 		}
-		w.targetWatch(target)
+		semaphore <- true
+		waitGroup.Add(1)
+		go func(targetName string, target *contexter.Target) {
+			defer waitGroup.Done()
+			w.targetWatch(targetName, target, true)
+			<-semaphore
+		}(targetName, target)
 	}
+	waitGroup.Wait()
 	w.update(watcherContext)
 }
 
 // Start is run 
 func (w *Watcher) Start() {
 	atomic.StoreUint32(&w.running, 1)
+	w.scheduler.start()
 	go w.watchLoop()
 }
 
 // Stop is stop
 func (w *Watcher) Stop() {
 	atomic.StoreUint32(&w.running, 0)
+	w.scheduler.stop()
+	w.saveState(w.context.GetWatcher())
 }
 
 // New is create Wathcer
@@ -234,10 +544,18 @@ func New(context *contexter.Context, notifier *notifier.Notifier) (*Watcher) {
         if err != nil {
                 hostname = "unknown"
         }
-	return &Watcher{
-		hostname: hostname,
-		context:  context,
-		running:  0,
-		notifier: notifier,
+	var newPersister *persister.Persister
+	if statePath := context.GetWatcher().StatePath; statePath != "" {
+		newPersister = persister.New(statePath)
+	}
+	newWatcher := &Watcher{
+		hostname:  hostname,
+		context:   context,
+		running:   0,
+		notifier:  notifier,
+		persister: newPersister,
 	}
+	newWatcher.updateChan = make(chan bool, 1)
+	newWatcher.scheduler = newScheduler(newWatcher, context.GetWatcher().WorkerCount)
+	return newWatcher
 }